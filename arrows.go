@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// arrowsSpacing is the distance between neighbouring nodes in the grid laid
+// out for Arrows.app. Arrows can re-layout the diagram, so a grid is enough.
+const arrowsSpacing = 200
+
+type arrowsDiagram struct {
+	Nodes         []arrowsNode         `json:"nodes"`
+	Relationships []arrowsRelationship `json:"relationships"`
+}
+
+type arrowsPosition struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+type arrowsNode struct {
+	ID         string            `json:"id"`
+	Caption    string            `json:"caption"`
+	Position   arrowsPosition    `json:"position"`
+	Labels     []string          `json:"labels"`
+	Properties map[string]string `json:"properties"`
+	Style      map[string]string `json:"style"`
+}
+
+type arrowsRelationship struct {
+	ID         string            `json:"id"`
+	FromID     string            `json:"fromId"`
+	ToID       string            `json:"toId"`
+	Type       string            `json:"type"`
+	Properties map[string]string `json:"properties"`
+	Style      map[string]string `json:"style"`
+}
+
+// writeArrows writes the graph as an Arrows.app JSON document.
+func writeArrows(w io.Writer, m *module) error {
+	columns := int(math.Ceil(math.Sqrt(float64(len(m.Indexes)))))
+
+	diagram := arrowsDiagram{
+		Nodes:         make([]arrowsNode, 0, len(m.Indexes)),
+		Relationships: make([]arrowsRelationship, 0),
+	}
+	for i := range m.Indexes {
+		properties := make(map[string]string)
+		if published, ok := m.PublishTimes[i]; ok {
+			properties["published"] = published.Format(time.RFC3339)
+		}
+		diagram.Nodes = append(diagram.Nodes, arrowsNode{
+			ID:      fmt.Sprintf("n%d", i),
+			Caption: m.nodeLabel(i),
+			Position: arrowsPosition{
+				X: (i % columns) * arrowsSpacing,
+				Y: (i / columns) * arrowsSpacing,
+			},
+			Labels:     make([]string, 0),
+			Properties: properties,
+			Style:      make(map[string]string),
+		})
+	}
+	var sizes []int
+	if *weights {
+		sizes = m.subtreeSizes()
+	}
+	for i := range m.Indexes {
+		for _, child := range m.Packages[i] {
+			properties := make(map[string]string)
+			if *weights {
+				properties["weight"] = strconv.Itoa(sizes[child])
+			}
+			diagram.Relationships = append(diagram.Relationships, arrowsRelationship{
+				ID:         fmt.Sprintf("r%d", len(diagram.Relationships)),
+				FromID:     fmt.Sprintf("n%d", i),
+				ToID:       fmt.Sprintf("n%d", child),
+				Type:       "REQUIRES",
+				Properties: properties,
+				Style:      make(map[string]string),
+			})
+		}
+	}
+
+	return writeJSONValue(w, diagram, &arrowsDiagram{})
+}
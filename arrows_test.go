@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestArrows(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "arrows")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var diagram arrowsDiagram
+	if err := json.Unmarshal([]byte(stdout), &diagram); err != nil {
+		t.Fatalf("output isn't an Arrows.app document: %v\n%s", err, stdout)
+	}
+
+	captions := make(map[string]string)
+	for _, node := range diagram.Nodes {
+		captions[node.ID] = node.Caption
+	}
+	if len(captions) != len(diagram.Nodes) {
+		t.Errorf("node ids aren't unique: %+v", diagram.Nodes)
+	}
+	edges := make([]string, 0, len(diagram.Relationships))
+	for _, r := range diagram.Relationships {
+		if r.Type != "REQUIRES" {
+			t.Errorf("relationship %s has type %q, want REQUIRES", r.ID, r.Type)
+		}
+		edges = append(edges, captions[r.FromID]+" -> "+captions[r.ToID])
+	}
+	want := []string{
+		"example.com/app -> example.com/a v1.0.0",
+		"example.com/app -> example.com/b v1.1.0",
+		"example.com/app -> example.com/missing v1.0.0",
+		"example.com/a v1.0.0 -> example.com/b v1.0.0",
+		"example.com/a v1.0.0 -> example.com/c v1.0.0",
+		"example.com/b v1.0.0 -> example.com/c v1.0.0",
+		"example.com/b v1.1.0 -> example.com/c v1.0.0",
+	}
+	if !reflect.DeepEqual(edges, want) {
+		t.Errorf("relationships =\n%q\nwant\n%q", edges, want)
+	}
+}
+
+func TestArrowsWeights(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-weights", "-format", "arrows")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var diagram arrowsDiagram
+	if err := json.Unmarshal([]byte(stdout), &diagram); err != nil {
+		t.Fatalf("output isn't an Arrows.app document: %v\n%s", err, stdout)
+	}
+	weights := make([]string, 0, len(diagram.Relationships))
+	for _, r := range diagram.Relationships {
+		weights = append(weights, r.Properties["weight"])
+	}
+	if want := []string{"3", "2", "1", "2", "1", "1", "1"}; !reflect.DeepEqual(weights, want) {
+		t.Errorf("relationship weights = %q, want %q", weights, want)
+	}
+}
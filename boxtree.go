@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+const (
+	colorVersion = "\x1b[36m"
+	colorFaint   = "\x1b[2m"
+	colorReset   = "\x1b[0m"
+)
+
+// boxTreeFormat is the tree drawn with box-drawing characters, like the tree
+// command draws directories. Each module's requirements are drawn once, and
+// every later occurrence is marked (*) instead.
+type boxTreeFormat struct{}
+
+func (boxTreeFormat) write(w io.Writer, m *module, depth int) error {
+	t := boxTree{
+		w:        w,
+		m:        m,
+		color:    isTerminal(w),
+		expanded: make(map[int]int),
+	}
+	fmt.Fprintln(w, t.label(0))
+	t.expanded[0] = depth
+	t.printChildren(0, "", depth)
+	return nil
+}
+
+func (boxTreeFormat) extension() string {
+	return ".txt"
+}
+
+// boxTree is the state of drawing a box tree.
+type boxTree struct {
+	w     io.Writer
+	m     *module
+	color bool
+	// expanded holds the depth each module's requirements were drawn to.
+	expanded map[int]int
+}
+
+// printChildren draws the requirements of the module at index i, each line
+// starting with prefix, stopping at the given depth.
+func (t boxTree) printChildren(i int, prefix string, depth int) {
+	if depth == 0 {
+		return
+	}
+	children := t.m.Packages[i]
+	for pos, child := range children {
+		branch, indent := "├── ", "│   "
+		if pos == len(children)-1 {
+			branch, indent = "└── ", "    "
+		}
+		grandchildren, resolved := t.m.Packages[child]
+		if !resolved || len(grandchildren) == 0 || depth == 1 {
+			fmt.Fprintln(t.w, prefix+branch+t.label(child))
+			continue
+		}
+		// A module drawn at least this deep before, including one of its own
+		// ancestors in a cycle, is only marked.
+		if prev, ok := t.expanded[child]; ok && deeper(prev, depth-1) {
+			fmt.Fprintln(t.w, prefix+branch+t.label(child)+t.faint(" (*)"))
+			continue
+		}
+		t.expanded[child] = depth - 1
+		fmt.Fprintln(t.w, prefix+branch+t.label(child))
+		t.printChildren(child, prefix+indent, depth-1)
+	}
+}
+
+// label returns the tree label of the module at index i with its version
+// coloured.
+func (t boxTree) label(i int) string {
+	name := t.m.Indexes[i]
+	suffix := strings.TrimPrefix(t.m.treeLabel(i), name)
+	modPath, version := deptree.SplitModuleName(name)
+	if version == "" {
+		return name + suffix
+	}
+	if t.color {
+		version = colorVersion + version + colorReset
+	}
+	return modPath + " " + version + suffix
+}
+
+// faint returns s dimmed if the tree is coloured.
+func (t boxTree) faint(s string) string {
+	if !t.color {
+		return s
+	}
+	return colorFaint + s + colorReset
+}
+
+// isTerminal reports whether w is a terminal that should be written to in
+// colour, which the NO_COLOR environment variable turns off.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
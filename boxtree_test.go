@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestBoxTree(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "tree")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `example.com/app
+├── example.com/a v1.0.0
+│   ├── example.com/b v1.0.0
+│   │   └── example.com/c v1.0.0
+│   └── example.com/c v1.0.0
+├── example.com/b v1.1.0
+│   └── example.com/c v1.0.0
+└── example.com/missing v1.0.0
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestBoxTreeMarksRepeats(t *testing.T) {
+	m := newModule(deptree.NewGraph())
+	for _, name := range []string{
+		"example.com/app",
+		"example.com/a v1.0.0",
+		"example.com/b v1.0.0",
+		"example.com/c v1.0.0",
+	} {
+		m.Index(name)
+	}
+	// b is required twice, and c requires a back.
+	m.Packages[0] = []int{1, 2}
+	m.Packages[1] = []int{2, 3}
+	m.Packages[2] = []int{3}
+	m.Packages[3] = []int{1}
+
+	var b bytes.Buffer
+	if err := (boxTreeFormat{}).write(&b, m, -1); err != nil {
+		t.Fatal(err)
+	}
+	want := `example.com/app
+├── example.com/a v1.0.0
+│   ├── example.com/b v1.0.0
+│   │   └── example.com/c v1.0.0
+│   │       └── example.com/a v1.0.0 (*)
+│   └── example.com/c v1.0.0 (*)
+└── example.com/b v1.0.0 (*)
+`
+	if got := b.String(); got != want {
+		t.Errorf("output =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// printCoalesced writes the requirements whose versions were replaced by the
+// root module's selection.
+func printCoalesced(w io.Writer, m *module) {
+	if len(m.Coalesced) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Coalesced versions:")
+	for _, c := range m.Coalesced {
+		fmt.Fprintln(w, "  "+c.Parent+" requires "+c.Module+" "+c.Required+", using "+c.Selected)
+	}
+}
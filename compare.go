@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// goModDiff is the difference between the requires of two go.mod files.
+type goModDiff struct {
+	Added   []string        `json:"added"`
+	Removed []string        `json:"removed"`
+	Changed []versionChange `json:"changed"`
+}
+
+// versionChange is a module required at a different version.
+type versionChange struct {
+	Module string `json:"module"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// readRequires reads the requires of the go.mod at filePath, which may also
+// be the directory holding it, keyed by module path.
+func readRequires(filePath string) (map[string]string, error) {
+	if info, err := os.Stat(filePath); err == nil && info.IsDir() {
+		filePath = path.Join(filePath, "go.mod")
+	}
+	mod, err := deptree.ParseGoMod(filePath)
+	if err != nil {
+		return nil, err
+	}
+	requires := make(map[string]string)
+	for _, require := range mod.Requires {
+		requires[require.Path] = require.Version
+	}
+	return requires, nil
+}
+
+// compareGoMods compares the requires of two go.mod files without walking
+// either of their dependency trees.
+func compareGoMods(from, to string) (goModDiff, error) {
+	before, err := readRequires(from)
+	if err != nil {
+		return goModDiff{}, err
+	}
+	after, err := readRequires(to)
+	if err != nil {
+		return goModDiff{}, err
+	}
+
+	diff := goModDiff{
+		Added:   make([]string, 0),
+		Removed: make([]string, 0),
+		Changed: make([]versionChange, 0),
+	}
+	for modPath, version := range after {
+		if old, ok := before[modPath]; !ok {
+			diff.Added = append(diff.Added, modPath+" "+version)
+		} else if old != version {
+			diff.Changed = append(diff.Changed, versionChange{Module: modPath, From: old, To: version})
+		}
+	}
+	for modPath, version := range before {
+		if _, ok := after[modPath]; !ok {
+			diff.Removed = append(diff.Removed, modPath+" "+version)
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Slice(diff.Changed, func(a, b int) bool {
+		return diff.Changed[a].Module < diff.Changed[b].Module
+	})
+	return diff, nil
+}
+
+// writeGoModDiff writes the difference between two go.mod files.
+func writeGoModDiff(w io.Writer, format string, diff goModDiff) error {
+	if format == "json" {
+		return writeJSONValue(w, diff, &goModDiff{})
+	}
+	fmt.Fprintln(w, "Added:")
+	for _, name := range diff.Added {
+		fmt.Fprintln(w, "  "+name)
+	}
+	fmt.Fprintln(w, "Removed:")
+	for _, name := range diff.Removed {
+		fmt.Fprintln(w, "  "+name)
+	}
+	fmt.Fprintln(w, "Changed:")
+	for _, change := range diff.Changed {
+		fmt.Fprintln(w, "  "+change.Module+": "+change.From+" -> "+change.To)
+	}
+	return nil
+}
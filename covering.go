@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// coveringSet returns a small set of the root's direct requirements whose
+// subtrees between them reach every module the root does. Finding the minimum
+// set is the set cover problem, so this uses the usual greedy approximation of
+// repeatedly taking the requirement that reaches the most modules not yet
+// covered. A requirement left out of the set only contributes modules that
+// other requirements already pull in, so removing it prunes the fewest modules.
+func (m *module) coveringSet() []int {
+	reachable := m.reachableSets()
+
+	covered := newBitset(len(m.Indexes))
+	covered.add(0)
+	remaining := make([]int, 0, len(m.Packages[0]))
+	for _, child := range m.Packages[0] {
+		if child != 0 {
+			remaining = append(remaining, child)
+		}
+	}
+
+	set := make([]int, 0)
+	for {
+		best, bestCount := -1, 0
+		for pos, child := range remaining {
+			if count := reachable[child].countMissing(covered); count > bestCount {
+				best, bestCount = pos, count
+			}
+		}
+		if best < 0 {
+			return set
+		}
+		set = append(set, remaining[best])
+		covered.union(reachable[remaining[best]])
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+}
+
+// printCoveringSet writes the covering set of the root's direct requirements.
+func printCoveringSet(w io.Writer, m *module) {
+	fmt.Fprintln(w, "Covering set:")
+	for _, i := range m.coveringSet() {
+		fmt.Fprintln(w, "  "+m.Indexes[i])
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// cvssWeights holds the weight of each value of each base metric of a CVSS
+// v3 vector. Privileges required weigh more when the scope changes, which
+// cvssScore handles.
+var cvssWeights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"PR": {"N": 0.85, "L": 0.62, "H": 0.27},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvssScore returns the base score of a CVSS v3 vector such as
+// "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", following the
+// specification's formula, and false if the vector isn't one.
+func cvssScore(vector string) (float64, bool) {
+	parts := strings.Split(vector, "/")
+	if len(parts) == 0 || !strings.HasPrefix(parts[0], "CVSS:3") {
+		return 0, false
+	}
+	values := make(map[string]string)
+	for _, part := range parts[1:] {
+		if kv := strings.SplitN(part, ":", 2); len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+	weights := make(map[string]float64)
+	for metric, table := range cvssWeights {
+		weight, ok := table[values[metric]]
+		if !ok {
+			return 0, false
+		}
+		weights[metric] = weight
+	}
+	changed := values["S"] == "C"
+	if !changed && values["S"] != "U" {
+		return 0, false
+	}
+	if changed {
+		weights["PR"] = map[string]float64{"N": 0.85, "L": 0.68, "H": 0.5}[values["PR"]]
+	}
+
+	iss := 1 - (1-weights["C"])*(1-weights["I"])*(1-weights["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, true
+	}
+	exploitability := 8.22 * weights["AV"] * weights["AC"] * weights["PR"] * weights["UI"]
+	if changed {
+		return cvssRoundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return cvssRoundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// cvssRoundUp rounds x up to one decimal place the way the CVSS v3.1
+// specification does, avoiding floating point errors such as 4.000001
+// rounding up to 4.1.
+func cvssRoundUp(x float64) float64 {
+	n := int(math.Round(x * 100000))
+	if n%10000 == 0 {
+		return float64(n) / 100000
+	}
+	return float64(n/10000+1) / 10
+}
+
+// cvssRating returns the qualitative rating of a CVSS score, as named in
+// severities, or an empty string for a score of 0.
+func cvssRating(score float64) string {
+	switch {
+	case score >= 9:
+		return "critical"
+	case score >= 7:
+		return "high"
+	case score >= 4:
+		return "medium"
+	case score > 0:
+		return "low"
+	}
+	return ""
+}
+
+// severityRating returns the rating of an OSV severity score, which is
+// either a CVSS v3 vector or a bare number.
+func severityRating(score string) string {
+	if n, err := strconv.ParseFloat(score, 64); err == nil {
+		return cvssRating(n)
+	}
+	if n, ok := cvssScore(score); ok {
+		return cvssRating(n)
+	}
+	return ""
+}
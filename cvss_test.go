@@ -0,0 +1,70 @@
+package main
+
+import "testing"
+
+func TestCVSSScore(t *testing.T) {
+	// The scores are those NVD publishes for each vector.
+	tests := []struct {
+		name   string
+		vector string
+		want   float64
+		ok     bool
+	}{
+		{name: "log4shell", vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", want: 10, ok: true},
+		{name: "critical unchanged scope", vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", want: 9.8, ok: true},
+		{name: "heartbleed", vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:N/A:N", want: 7.5, ok: true},
+		{name: "local privilege escalation", vector: "CVSS:3.1/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:H/A:H", want: 7.8, ok: true},
+		{name: "reflected xss", vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", want: 6.1, ok: true},
+		{name: "changed scope privileges", vector: "CVSS:3.1/AV:N/AC:L/PR:L/UI:N/S:C/C:L/I:L/A:N", want: 6.4, ok: true},
+		{name: "cvss 3.0", vector: "CVSS:3.0/AV:N/AC:H/PR:N/UI:N/S:U/C:H/I:N/A:N", want: 5.9, ok: true},
+		{name: "no impact", vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", want: 0, ok: true},
+		{name: "cvss 2", vector: "AV:N/AC:L/Au:N/C:P/I:P/A:P", ok: false},
+		{name: "missing metric", vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H", ok: false},
+		{name: "unknown scope", vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:X/C:H/I:H/A:H", ok: false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := cvssScore(test.vector)
+			if ok != test.ok || got != test.want {
+				t.Errorf("cvssScore() = %v, %v, want %v, %v", got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
+
+func TestCVSSRoundUp(t *testing.T) {
+	tests := []struct {
+		x    float64
+		want float64
+	}{
+		{x: 4, want: 4},
+		{x: 4.000001, want: 4},
+		{x: 4.02, want: 4.1},
+		{x: 9.75, want: 9.8},
+	}
+	for _, test := range tests {
+		if got := cvssRoundUp(test.x); got != test.want {
+			t.Errorf("cvssRoundUp(%v) = %v, want %v", test.x, got, test.want)
+		}
+	}
+}
+
+func TestSeverityRating(t *testing.T) {
+	tests := []struct {
+		score string
+		want  string
+	}{
+		{score: "9.0", want: "critical"},
+		{score: "7.5", want: "high"},
+		{score: "4", want: "medium"},
+		{score: "0.1", want: "low"},
+		{score: "0", want: ""},
+		{score: "CVSS:3.1/AV:N/AC:L/PR:N/UI:R/S:C/C:L/I:L/A:N", want: "medium"},
+		{score: "not a score", want: ""},
+	}
+	for _, test := range tests {
+		if got := severityRating(test.score); got != test.want {
+			t.Errorf("severityRating(%q) = %q, want %q", test.score, got, test.want)
+		}
+	}
+}
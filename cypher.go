@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// cypherBatchSize is the number of rows in each UNWIND statement, keeping
+// statements small enough for the Neo4j browser to run comfortably.
+const cypherBatchSize = 500
+
+var cypherEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// writeCypher writes the graph as Cypher statements creating a Module node for
+// every module and a DEPENDS_ON relationship for every requirement. Nodes are
+// identified by their index so relationships can be matched back to them.
+// With -licenses each node also has a license property.
+func writeCypher(w io.Writer, m *module) error {
+	create := "CREATE (:Module {id: row.id, path: row.path, version: row.version});"
+	if m.licenses != nil {
+		create = "CREATE (:Module {id: row.id, path: row.path, version: row.version, license: row.license});"
+	}
+	nodes := make([]string, 0, len(m.Indexes))
+	for i, name := range m.Indexes {
+		modPath, version := deptree.SplitModuleName(name)
+		node := fmt.Sprintf(`id: %d, path: "%s", version: "%s"`, i, cypherEscaper.Replace(modPath), cypherEscaper.Replace(version))
+		// A missing license is null, which Neo4j doesn't store.
+		if license, ok := m.licenses[i]; ok {
+			node += fmt.Sprintf(`, license: "%s"`, cypherEscaper.Replace(license))
+		}
+		nodes = append(nodes, "{"+node+"}")
+	}
+	edges := make([]string, 0)
+	for i := range m.Indexes {
+		for _, child := range m.Packages[i] {
+			edges = append(edges, fmt.Sprintf(`{from: %d, to: %d}`, i, child))
+		}
+	}
+
+	if _, err := fmt.Fprintln(w, "CREATE INDEX IF NOT EXISTS FOR (n:Module) ON (n.id);"); err != nil {
+		return err
+	}
+	for start := 0; start < len(nodes); start += cypherBatchSize {
+		end := start + cypherBatchSize
+		if end > len(nodes) {
+			end = len(nodes)
+		}
+		if _, err := fmt.Fprintf(w, "UNWIND [\n  %s\n] AS row\n%s\n", strings.Join(nodes[start:end], ",\n  "), create); err != nil {
+			return err
+		}
+	}
+	for start := 0; start < len(edges); start += cypherBatchSize {
+		end := start + cypherBatchSize
+		if end > len(edges) {
+			end = len(edges)
+		}
+		if _, err := fmt.Fprintf(w, "UNWIND [\n  %s\n] AS row\nMATCH (a:Module {id: row.from}), (b:Module {id: row.to})\nCREATE (a)-[:DEPENDS_ON]->(b);\n", strings.Join(edges[start:end], ",\n  ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestCypher(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "cypher")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `CREATE INDEX IF NOT EXISTS FOR (n:Module) ON (n.id);
+UNWIND [
+  {id: 0, path: "example.com/app", version: ""},
+  {id: 1, path: "example.com/a", version: "v1.0.0"},
+  {id: 2, path: "example.com/b", version: "v1.0.0"},
+  {id: 3, path: "example.com/c", version: "v1.0.0"},
+  {id: 4, path: "example.com/b", version: "v1.1.0"},
+  {id: 5, path: "example.com/missing", version: "v1.0.0"}
+] AS row
+CREATE (:Module {id: row.id, path: row.path, version: row.version});
+UNWIND [
+  {from: 0, to: 1},
+  {from: 0, to: 4},
+  {from: 0, to: 5},
+  {from: 1, to: 2},
+  {from: 1, to: 3},
+  {from: 2, to: 3},
+  {from: 4, to: 3}
+] AS row
+MATCH (a:Module {id: row.from}), (b:Module {id: row.to})
+CREATE (a)-[:DEPENDS_ON]->(b);
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestCypherBatches(t *testing.T) {
+	m := newModule(deptree.NewGraph())
+	root := m.Index("example.com/app")
+	children := make([]int, 0, cypherBatchSize+1)
+	for i := 0; i <= cypherBatchSize; i++ {
+		children = append(children, m.Index(fmt.Sprintf(`example.com/"quoted"\%d v1.0.0`, i)))
+	}
+	m.Packages[root] = children
+
+	var b bytes.Buffer
+	if err := writeCypher(&b, m); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	// Two batches of nodes, as the root makes one more than a batch, and two
+	// of relationships.
+	if got := strings.Count(out, "UNWIND ["); got != 4 {
+		t.Errorf("%d UNWIND statements, want 4", got)
+	}
+	if want := `{id: 1, path: "example.com/\"quoted\"\\0", version: "v1.0.0"}`; !strings.Contains(out, want) {
+		t.Errorf("output doesn't escape quotes and backslashes, want %s in\n%s", want, out[:200])
+	}
+}
@@ -1,26 +1,103 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"path"
-	"regexp"
+	"runtime"
 	"strings"
+	"time"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
 )
 
-var gopath = ""
 var maxDepth = flag.Int("maxDepth", -1, "Maximum recursion level to scan, -1 for no limit, otherwise must be an integer greater than 0, ignored if -find specified. Defaults to -1.")
-var modulePath = flag.String("modulePath", ".", "Path to module to scan, can be relative or absolute. Defaults to current working directory.")
+var modulePaths = newPathList("modulePath", ".", "Path to module to scan, or to its go.mod file, can be relative or absolute. Give it more than once to scan several modules at once and print a merged report, which only supports the text and json formats. Defaults to current working directory.")
+var modulePathsFile = flag.String("modulePathsFile", "", "File listing the paths of modules to scan, one per line, as if each were given to -modulePath. Blank lines and lines starting with # are skipped.")
 var versionFlag = flag.Bool("version", false, "Print out go-tree version.")
-var searchText = flag.String("find", "", "Search for a specific module. Useful for if you're looking for the dependency chain for a specific module. If not set, the program will print out the entire tree.")
-
-type dependencyChain struct {
-	module   string
-	children []dependencyChain
-}
+var searchText = flag.String("find", "", "Print the shortest dependency paths from the root module to the module with this path, optionally followed by a version, instead of the whole tree. Exits with an error if the module isn't in the tree.")
+var requireCleanTree = flag.Bool("requireCleanTree", false, "Exit with an error and print a report if the dependency tree has any unknown modules, cycles, unreadable go.mod files, broken replaces, path mismatches or self-references, ignored if -find specified.")
+var trace = flag.Bool("trace", false, "Log every module resolution decision to stderr, useful for diagnosing why a module couldn't be found.")
+var prefix = flag.String("prefix", "", "Comma separated list of module path prefixes, only output the modules matching one of them and the requires between them. Modules the root no longer reaches once the others are dropped are pruned from the output too.")
+var withInfo = flag.Bool("withInfo", false, "Read each module's publish time from the .info files in the module download cache and include them in the output.")
+var publishedAfter = flag.String("publishedAfter", "", "Report the modules published after the given RFC3339 time, read from the .info files in the module download cache.")
+var noRecurseUnknownVersions = flag.Bool("noRecurseUnknownVersions", false, "Only walk a module if the exact version it's required at is in the module cache, treating any other version as unknown.")
+var weights = flag.Bool("weights", false, "Weight each edge by the number of distinct modules reachable through it, shown in the dot, svg, arrows and mermaid formats.")
+var dedupeSubtrees = flag.Bool("dedupeSubtrees", false, "In text output, print each module's requirements only once and refer back to them by id wherever the module appears again.")
+var noGoEnv = flag.Bool("noGoEnv", false, "Don't run go env to discover GOPATH and GOMODCACHE when they aren't set in the environment.")
+var downgradeRisk = flag.Bool("downgradeRisk", false, "Report the modules required at versions spanning more than one major or minor release across the tree.")
+var validateOutput = flag.Bool("validateOutput", false, "Check that json and arrows output survives a round trip through its schema before writing it, exiting with an error if it doesn't.")
+var dtrackURL = flag.String("dtrackUrl", "", "Upload a CycloneDX BOM of the dependency tree to the OWASP Dependency-Track server at this URL. The API key is read from -dtrackApiKey or the DTRACK_API_KEY environment variable.")
+var dtrackAPIKey = flag.String("dtrackApiKey", "", "API key to upload to Dependency-Track with. Defaults to the DTRACK_API_KEY environment variable.")
+var dtrackProjectVersion = flag.String("dtrackProjectVersion", "", "Project version to upload the BOM to Dependency-Track under.")
+var recursive = flag.Bool("recursive", false, "Scan every module found under -modulePath, skipping vendor, testdata and hidden directories, and print the combined output keyed by module directory. Only supports the text and json formats.")
+var maxSamePathVersions = flag.Int("maxSamePathVersions", 0, "Exit with an error and print a report if any module path is required at more than this many distinct versions across the tree, 0 for no limit.")
+var suspectIndirect = flag.Bool("suspectIndirect", false, "Report the root module's requires marked // indirect that are also the most commonly required modules in the tree. This is a heuristic, check the results with go mod tidy.")
+var strictSemver = flag.Bool("strictSemver", false, "Report every module whose version isn't valid semver, which points to a corrupt go.mod.")
+var coveringSet = flag.Bool("coveringSet", false, "Report a small set of the root module's direct requirements whose subtrees between them reach every module in the tree.")
+var noWalkUp = flag.Bool("noWalkUp", false, "Don't search the parent directories of -modulePath for a go.mod when it doesn't have one of its own.")
+var coalesceVersionsInTree = flag.Bool("coalesceVersionsInTree", false, "Walk every module the root module requires at the version the root requires it at, as the build would, and report where that replaced the version a parent asked for.")
+var packages = flag.Bool("packages", false, "Parse the imports of every package in the root module and report which packages of the modules in the tree they import. This reads every Go file in the module, so is much slower on large modules.")
+var output = flag.String("output", "", "File to write the output to instead of stdout.")
+var tee = flag.Bool("tee", false, "Write the output to stdout as well as the -output file.")
+var allowMissingGopath = flag.Bool("allowMissingGopath", false, "Carry on when neither GOPATH nor GOMODCACHE could be found, treating every dependency as unknown.")
+var compare = flag.String("compare", "", "Compare the requires of two go.mod files, given as <gomodA>,<gomodB>, and report those added, removed or changed in the second. Only the two files are read, not the rest of the tree. Only supports the text and json formats.")
+var showStats = flag.Bool("stats", false, "Report statistics about the tree, flagging when the number of modules the root go.mod requires and the number reached differ by more than 10%, with direct and indirect module counts, the maximum depth and the top 10 modules by fan-in and fan-out.")
+var indentText = flag.String("indent", "  ", "String to indent each level of the text tree with, \\t for a tab. Defaults to two spaces.")
+var unusedReplaces = flag.Bool("unusedReplaces", false, "Report the root module's replace directives for module paths that aren't required anywhere in the tree.")
+var firstParty = flag.String("firstParty", "", "Comma separated list of module path prefixes of your own modules, used by -onlyFirstPartyEdges.")
+var onlyFirstPartyEdges = flag.Bool("onlyFirstPartyEdges", false, "Only output the modules matching -firstParty and the requires between them, dropping every third party module.")
+var showGraphStats = flag.Bool("graphStats", false, "Report the in-degree and out-degree distributions of the tree, its average degree and the modules with the most dependencies and dependents.")
+var fixtureRoot = flag.String("fixtureRoot", "", "Resolve everything from this directory: it is used as GOPATH, its pkg/mod as GOMODCACHE, and a relative -modulePath is taken relative to it.")
+var showFanout = flag.Bool("showFanout", false, "Append the number of modules each module requires to its label in the dot, tf-dot, svg, mermaid, pajek and arrows formats.")
+var explainUnknown = flag.String("explainUnknown", "", "Explain how a module was resolved, listing every path tried for it and why each failed, instead of printing the tree. Give the module path, optionally with a version.")
+var redact = flag.String("redact", "", "Comma separated list of module path prefixes whose paths are replaced by stable hashes in the output and the reports of failed checks, so the tree can be shared without revealing private module names.")
+var redactVersions = flag.Bool("redactVersions", false, "Also replace the versions of modules matching -redact with stable hashes.")
+var jobs = flag.Int("jobs", runtime.GOMAXPROCS(0), "Most go.mod files to read at once, 1 to read them one by one. The output is the same whatever the number. Defaults to GOMAXPROCS.")
+var vendor = flag.Bool("vendor", false, "Read the dependencies from vendor/modules.txt instead of the module cache. This is the default when vendor/modules.txt exists.")
+var noVendor = flag.Bool("noVendor", false, "Don't read the dependencies from vendor/modules.txt even if it exists.")
+var rdeps = flag.String("rdeps", "", "Print every module directly requiring the module with this path, at any version, along with the version each requires, instead of the whole tree. Exits with an error if nothing requires it. Only supports the text and json formats.")
+var transitive = flag.Bool("transitive", false, "With -rdeps, print every module requiring the module, which may be followed by a version, directly or through other modules, with the shortest path from the root to each.")
+var reverse = flag.String("reverse", "", "Same as -rdeps with -transitive: print every module requiring the module with this path, optionally followed by a version, directly or through other modules.")
+var directOnly = flag.Bool("directOnly", false, "Only list the modules the root module requires, marking those its go.mod marks // indirect, rather than the whole tree. Overrides -maxDepth.")
+var showDepths = flag.Bool("depths", false, "Report the fewest requires separating each module from the root module.")
+var strict = flag.Bool("strict", false, "Exit with an error if the go.mod of any module in the tree couldn't be read, so an incomplete tree doesn't go unnoticed.")
+var proxy = flag.Bool("proxy", true, "Fetch the go.mod files of modules missing from GOPATH from the module proxy, honouring GOPROXY and GONOPROXY, and cache them for later runs. Ignored with -fixtureRoot.")
+var resolved = flag.Bool("resolved", false, "Report the version minimal version selection picks for every module path in the tree, along with every version it's required at.")
+var vuln = flag.Bool("vuln", false, "Query the OSV database for known vulnerabilities in the version of every module path minimal version selection picks, reporting each with the path of requires pulling it in. Exits with an error if any is rated at or above -vulnSeverity.")
+var vulnSeverity = flag.String("vulnSeverity", "low", "Lowest severity of vulnerability that makes -vuln exit with an error, one of low, moderate, high or critical. Vulnerabilities without a rating, from their database, CVSS score or any alias, always do.")
+var vulnURL = flag.String("vulnUrl", "https://api.osv.dev", "Base URL of the OSV API -vuln queries.")
+var include = flag.String("include", "", "Comma separated list of module path patterns, in the format of GOPRIVATE, to limit the walk to. Modules not matching are neither listed nor walked.")
+var exclude = flag.String("exclude", "", "Comma separated list of module path patterns, in the format of GOPRIVATE, to leave out of the walk. Matching modules are neither listed nor walked.")
+var pruneIndirect = flag.Bool("pruneIndirect", false, "Don't walk the requirements of modules required // indirect, unless something requires them directly.")
+var diffTreesFlag = flag.String("diff", "", "Compare the dependency trees of two modules, given as <dirA>,<dirB>, and report the module paths added, removed or resolved to a different version in the second, with the modules requiring each. Only supports the text and json formats.")
+var policyFile = flag.String("policy", "", "JSON file of rules the resolved dependency tree has to follow: banned module patterns, the highest version allowed for a module path, and whether more than one major version of a module is allowed. Exits with an error and prints a report of every violation.")
+var showLicenses = flag.Bool("licenses", false, "Classify the license of every module from its LICENSE, LICENCE or COPYING file, showing its SPDX identifier beside it in every format and a summary grouped by license.")
+var allowedLicenses = flag.String("allowedLicenses", "", "Comma separated list of SPDX license identifiers. Exits with an error and prints a report if any module has a license not listed, or one that couldn't be recognised.")
+var serve = flag.String("serve", "", "Serve a page to explore the dependency tree with, and the JSON it's built on, at this address, such as localhost:8080, instead of writing the tree out.")
+var failOnCycle = flag.Bool("failOnCycle", false, "Exit with an error and print a report if the dependency tree has any cycles.")
+var noCache = flag.Bool("noCache", false, "Don't keep the parsed go.mod files of the module cache between runs, parsing every one each time.")
+var outdated = flag.Bool("outdated", false, "Ask the module proxy about newer versions of every module path in the tree, reporting those with a newer patch, minor or major release, and whether releases are retracted or the module deprecated. Needs the module proxy, so can't be used with -proxy=false or -fixtureRoot.")
+var granularity = flag.String("granularity", "module", "Granularity of the dependency graph, either module or package. package also parses the imports of every package in the root module, as -packages does, reporting which packages of the modules in the tree each imports, and writes the package import graph in every graph format.")
+var packageDeps = flag.Bool("packageDeps", false, "With -packages or -granularity package, also follow the imports of the packages imported from other modules, as far as their source is on disk, reporting the package imports of the dependencies too.")
+var showConflicts = flag.Bool("conflicts", false, "Report the module paths found at more than one major version, such as example.com/x/v2 and example.com/x/v3, or at more than one version, grouped by the path they share, with the spread of versions of each major version and a path of requires pulling in each version.")
+var failOnDuplicateMajor = flag.Bool("failOnDuplicateMajor", false, "Exit with an error and print a report if more than one major version of any module is found in the dependency tree.")
+var verify = flag.Bool("verify", false, "Check every module in the tree against the root module's go.sum, reporting those go.sum has no hash for and the go.sum entries no longer in the tree. Exits with an error if any module is missing from go.sum. Walks the whole tree whatever -maxDepth is.")
+var verifyHashes = flag.Bool("verifyHashes", false, "With -verify, also hash the source and go.mod of every module in the module cache, as go mod verify does, and exit with an error if any doesn't match go.sum.")
+var allPaths = flag.Bool("allPaths", false, "With -find, print every path from the root module to the module, not only the shortest, up to -maxPaths of them.")
+var maxPaths = flag.Int("maxPaths", 100, "The most paths -find -allPaths prints, as big trees can have a great many.")
+var format = flag.String("format", "text", "Output format, either text, tree (box-drawing tree), json, ndjson (newline delimited JSON streamed as the tree is walked), arrows (Arrows.app JSON), cypher (Neo4j), dot (Graphviz), tf-dot (terraform graph style DOT), svg (rendered with Graphviz), dependency-track (CycloneDX for OWASP Dependency-Track), cyclonedx (CycloneDX SBOM), spdx (SPDX SBOM), opml (outline), pajek (Pajek .net network), mermaid (Mermaid flowchart) or toposort (modules in dependency order), ignored if -find specified. Defaults to text.")
+var groupOutput = flag.String("groupOutput", "", "Directory to write the subtree of each direct dependency to, one file per dependency, instead of printing the whole tree. The files are JSON unless -format is given. Ignored if -find specified.")
+
+var publishedAfterTime time.Time
+
+// treePolicy is the policy read from -policy, nil if there isn't one.
+var treePolicy *policy
 
 func main() {
 	flag.Parse()
@@ -34,243 +111,584 @@ func main() {
 		fmt.Println("Invalid value supplied to for maxDepth, must either be -1 or an integer grater than 0")
 	}
 
-	cwd := *modulePath
-
-	if cwd == "." {
-		dir, err := os.Getwd()
-		if err != nil {
-			log.Println(err)
-			os.Exit(1)
-		}
-		cwd = dir
-	} else {
-		if !path.IsAbs(*modulePath) {
+	if _, ok := formats[*format]; !ok {
+		fmt.Println("Invalid value supplied for format, must be one of " + strings.Join(formatNames(), ", "))
+		os.Exit(1)
+	}
 
-			dir, err := os.Getwd()
-			if err != nil {
-				log.Println(err)
-				os.Exit(1)
-			}
-			cwd = path.Join(dir, *modulePath)
-		}
+	if *directOnly {
+		*maxDepth = 1
+	}
+	*indentText = strings.Replace(*indentText, `\t`, "\t", -1)
+
+	switch *granularity {
+	case "module":
+	case "package":
+		*packages = true
+	default:
+		fmt.Println("Invalid value supplied for granularity, possible values are module or package")
+		os.Exit(1)
 	}
 
-	gopath = os.Getenv("GOPATH")
+	if *maxPaths < 1 {
+		fmt.Println("Invalid value supplied for maxPaths, must be an integer greater than 0")
+		os.Exit(1)
+	}
 
-	modFile := path.Join(cwd, "go.mod")
-	if _, err := os.Stat(modFile); os.IsNotExist(err) {
-		println("ERROR: go.mod is not present in this directory, please only run this tool in the root of your go project or specify a path to the root directory of a go project")
+	if *jobs < 1 {
+		fmt.Println("Invalid value supplied for jobs, must be an integer greater than 0")
 		os.Exit(1)
 	}
 
-	if *searchText != "" {
-		chain := search(cwd)
-		if len(chain.children) != 0 {
-			printChain(chain, "")
-		} else {
-			fmt.Println("Unable to find module '" + *searchText + "' in dependency tree.")
-		}
-	} else {
-		getModuleList(getModuleName(cwd), "", *maxDepth)
+	if *tee && *output == "" {
+		fmt.Println("Invalid value supplied for tee, -tee requires -output")
+		os.Exit(1)
 	}
 
-	os.Exit(0)
-}
+	if *onlyFirstPartyEdges && *firstParty == "" {
+		fmt.Println("Invalid value supplied for onlyFirstPartyEdges, -onlyFirstPartyEdges requires -firstParty")
+		os.Exit(1)
+	}
 
-func printChain(chain dependencyChain, indent string) {
-	if len(chain.children) == 0 {
-		fmt.Println(indent + chain.module)
-	} else {
-		fmt.Println(indent + chain.module + ":")
-		for _, child := range chain.children {
-			printChain(child, indent+"  ")
+	if *reverse != "" {
+		if *rdeps != "" && *rdeps != *reverse {
+			fmt.Println("Invalid value supplied for reverse, -reverse and -rdeps name different modules")
+			os.Exit(1)
 		}
+		*rdeps, *transitive = *reverse, true
 	}
-}
 
-func search(cwd string) dependencyChain {
-	fmt.Println("Searching for " + *searchText)
+	if *rdeps != "" && *format != "text" && *format != "json" {
+		fmt.Println("Invalid value supplied for rdeps, must be used with the text or json format")
+		os.Exit(1)
+	}
 
-	return dependencyChain{
-		module:   getModuleName(cwd),
-		children: rescursiveFind(getModuleName(cwd)),
+	if *transitive && *rdeps == "" {
+		fmt.Println("Invalid value supplied for transitive, -transitive requires -rdeps")
+		os.Exit(1)
 	}
-}
 
-func rescursiveFind(module string) []dependencyChain {
-	children := make([]dependencyChain, 0)
-	rawPath, modFound := constructFilePath(escapeCapitalsInModuleName(module))
+	if _, ok := severities[*vulnSeverity]; !ok {
+		fmt.Println("Invalid value supplied for vulnSeverity, possible values are low, moderate, high or critical")
+		os.Exit(1)
+	}
 
-	if !modFound {
-		return children
+	// Minimal version selection needs every version of every module the
+	// build could use, which these leave out of the graph.
+	resolving := *resolved || *vuln || *policyFile != "" || *diffTreesFlag != "" || *outdated
+	if resolving && (*include != "" || *exclude != "" || *pruneIndirect || *coalesceVersionsInTree) {
+		fmt.Println("Invalid value supplied for resolved, -resolved, -vuln, -policy, -diff and -outdated need the whole tree to work out the versions the build uses, so can't be used with -include, -exclude, -pruneIndirect or -coalesceVersionsInTree")
+		os.Exit(1)
 	}
 
-	modFilePath := path.Join(rawPath, "go.mod")
-	fileBytes, err := ioutil.ReadFile(modFilePath)
+	if *policyFile != "" {
+		p, err := readPolicy(*policyFile)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		treePolicy = &p
+	}
 
-	if err != nil {
-		return children
+	if *publishedAfter != "" {
+		after, err := time.Parse(time.RFC3339, *publishedAfter)
+		if err != nil {
+			fmt.Println("Invalid value supplied for publishedAfter, must be an RFC3339 time such as 2020-01-02T15:04:05Z")
+			os.Exit(1)
+		}
+		publishedAfterTime = after
 	}
 
-	found := false
+	if *compare != "" {
+		files := strings.Split(*compare, ",")
+		if len(files) != 2 || (*format != "text" && *format != "json") {
+			fmt.Println("Invalid value supplied for compare, must be two go.mod files separated by a comma and used with the text or json format")
+			os.Exit(1)
+		}
+		diff, err := compareGoMods(files[0], files[1])
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		out, closeOutput, err := openOutput(*output, *tee)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if err := writeGoModDiff(out, *format, diff); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if err := closeOutput(); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
-	lines := strings.Split(string(fileBytes), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if !found {
-			if line == "require (" {
-				found = true
-			}
-		} else {
-			if line == "" {
-				// skip
-			} else if strings.Split(line, " ")[0] == *searchText {
-				children = append(children, dependencyChain{
-					module:   line,
-					children: make([]dependencyChain, 0),
-				})
-			} else if line == ")" {
-				return children
-			} else {
-				chain := rescursiveFind(line)
-				if len(chain) > 0 {
-					children = append(children, dependencyChain{
-						module:   line,
-						children: chain,
-					})
-				}
+	paths := modulePaths.values
+	if *modulePathsFile != "" {
+		listed, err := readPathsFile(*modulePathsFile)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if !modulePaths.set {
+			paths = nil
+		}
+		paths = append(paths, listed...)
+		if len(paths) == 0 {
+			fmt.Println("Invalid value supplied for modulePathsFile, it lists no module paths")
+			os.Exit(1)
+		}
+	}
+
+	base, err := os.Getwd()
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	// Within a fixture, module paths are relative to the fixture.
+	if *fixtureRoot != "" {
+		if !path.IsAbs(*fixtureRoot) {
+			*fixtureRoot = path.Join(base, *fixtureRoot)
+		}
+		base = *fixtureRoot
+	}
+	roots := make([]string, 0, len(paths))
+	for _, root := range paths {
+		if !path.IsAbs(root) {
+			root = path.Join(base, root)
+		}
+		// Users sometimes point at the go.mod itself rather than its
+		// directory.
+		if info, err := os.Stat(root); err == nil && !info.IsDir() && path.Base(root) == "go.mod" {
+			root = path.Dir(root)
+		}
+		roots = append(roots, root)
+	}
+	cwd := roots[0]
+	// Several modules are each scanned as given, with no walking up or
+	// workspaces, and merged into one report.
+	merged := len(roots) > 1
+	if merged && *format != "text" && *format != "json" {
+		fmt.Println("Invalid value supplied for format, scanning several modules only supports text or json")
+		os.Exit(1)
+	}
+	if merged && !*recursive {
+		for _, root := range roots {
+			if _, err := os.Stat(path.Join(root, "go.mod")); err != nil {
+				fmt.Println("ERROR: go.mod is not present in " + root + ", every module path given must be the root directory of a go project")
+				os.Exit(1)
 			}
 		}
 	}
-	return children
-}
 
-func getNameAndVersion(module string) (string, string) {
-	if strings.Contains(module, "@") {
-		s := strings.Split(module, "@")
-		return s[0], s[1]
+	gopath := os.Getenv("GOPATH")
+	modCache := os.Getenv("GOMODCACHE")
+	if *fixtureRoot != "" {
+		gopath = *fixtureRoot
+		modCache = path.Join(*fixtureRoot, "pkg", "mod")
 	}
-	s := strings.Split(module, " ")
-	if len(s) == 1 {
-		return s[0], ""
+	// Tools launched from an IDE often don't have GOPATH exported, so ask the
+	// go command for the values it would use.
+	if (gopath == "" || modCache == "") && !*noGoEnv {
+		envGopath, envModCache, err := deptree.GoEnv()
+		if err != nil && *trace {
+			log.Printf("trace: unable to run go env: %v", err)
+		}
+		if gopath == "" {
+			gopath = envGopath
+		}
+		if modCache == "" {
+			modCache = envModCache
+		}
 	}
-	return s[0], getSemVer(s[1])
-}
 
-func constructFilePath(dep string) (string, bool) {
-	module, version := getNameAndVersion(dep)
-	pkgPath := path.Join(gopath, "pkg", "mod", module+"@"+getSemVer(version))
-	fullVersionPkgPath := path.Join(gopath, "pkg", "mod", module+"@"+version)
-	srcPath := path.Join(gopath, "src", module)
+	if gopath == "" && modCache == "" && !*allowMissingGopath {
+		reason := "go env couldn't find them"
+		if *noGoEnv {
+			reason = "-noGoEnv stopped go env being used to find them"
+		}
+		fmt.Println("ERROR: GOPATH and GOMODCACHE are not set and " + reason + ", so no dependencies can be resolved. Set either of them, or pass -allowMissingGopath to list the tree anyway")
+		os.Exit(1)
+	}
 
-	if _, err := os.Stat(srcPath); err == nil || !os.IsNotExist(err) {
-		return srcPath, true
+	// Searches, diffs and anything working out the versions the build uses
+	// always look through the whole tree.
+	depth := *maxDepth
+	if *searchText != "" || *rdeps != "" || *verify || resolving {
+		depth = -1
 	}
 
-	if _, err := os.Stat(pkgPath); err == nil || !os.IsNotExist(err) {
-		return pkgPath, true
+	// Fixtures are meant to be self-contained, so never go to the network
+	// for them.
+	proxyList, noProxy := "", ""
+	if *proxy && *fixtureRoot == "" {
+		proxyList = os.Getenv("GOPROXY")
+		if proxyList == "" {
+			proxyList = deptree.DefaultProxy
+		}
+		noProxy = os.Getenv("GONOPROXY")
+		if noProxy == "" {
+			noProxy = os.Getenv("GOPRIVATE")
+		}
+	}
+	if *outdated && proxyList == "" {
+		fmt.Println("Invalid value supplied for outdated, newer versions are found on the module proxy, which -proxy=false and -fixtureRoot turn off")
+		os.Exit(1)
 	}
 
-	if _, err := os.Stat(fullVersionPkgPath); err == nil || !os.IsNotExist(err) {
-		return fullVersionPkgPath, true
+	// Fixtures are edited by hand, so unlike the real module cache theirs
+	// can change between runs.
+	parseCache := ""
+	if !*noCache && *fixtureRoot == "" {
+		if dir, err := os.UserCacheDir(); err == nil {
+			parseCache = path.Join(dir, "go-mod-dependency-tree", "parsed.json")
+		}
 	}
 
-	return "", false
-}
+	// Stop building the graph on an interrupt, rather than leaving
+	// downloads from the module proxy to finish first.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	go func() {
+		<-interrupts
+		cancel()
+		signal.Stop(interrupts)
+	}()
+
+	options := []deptree.Option{
+		deptree.WithContext(ctx),
+		deptree.WithMaxDepth(depth),
+		deptree.WithGopath(gopath),
+		deptree.WithModCache(modCache),
+		deptree.WithNoGoEnv(*noGoEnv),
+		deptree.WithExactVersions(*noRecurseUnknownVersions),
+		deptree.WithPublishTimes(*withInfo || *publishedAfter != ""),
+		deptree.WithTrace(*trace),
+		deptree.WithCoalesceVersions(*coalesceVersionsInTree),
+		deptree.WithJobs(*jobs),
+		deptree.WithProxy(proxyList),
+		deptree.WithNoProxy(noProxy),
+		deptree.WithIncludePaths(*include),
+		deptree.WithExcludePaths(*exclude),
+		deptree.WithPruneIndirect(*pruneIndirect),
+		deptree.WithParseCache(parseCache),
+	}
 
-func getModuleList(modPath, indent string, depth int) {
-	if depth == 0 {
-		fmt.Println(indent + strings.Split(modPath, " //")[0])
-		return
+	if *diffTreesFlag != "" {
+		dirs := strings.Split(*diffTreesFlag, ",")
+		if len(dirs) != 2 || (*format != "text" && *format != "json") {
+			fmt.Println("Invalid value supplied for diff, must be two module directories separated by a comma and used with the text or json format")
+			os.Exit(1)
+		}
+		from, err := buildTree(dirs[0], options...)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		to, err := buildTree(dirs[1], options...)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		out, closeOutput, err := openOutput(*output, *tee)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if err := writeTreeDiff(out, *format, diffTrees(from, to)); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if err := closeOutput(); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
-	rawPath, modFound := constructFilePath(escapeCapitalsInModuleName(modPath))
-	if !modFound {
-		fmt.Println(indent + strings.Split(modPath, " //")[0])
-		return
+
+	modFile := path.Join(cwd, "go.mod")
+	// Like the go command, a go.work in the module's directory or above it
+	// makes every module it uses part of one graph, even where there's a
+	// go.mod, unless GOWORK is off.
+	workFile := ""
+	if !*recursive && !merged {
+		if file, ok := deptree.FindWorkspace(cwd, !*noWalkUp); ok {
+			workFile = file
+			if path.Dir(file) != cwd {
+				fmt.Fprintln(os.Stderr, "Using workspace "+file)
+			}
+		}
+	}
+	if _, err := os.Stat(modFile); os.IsNotExist(err) && !*recursive && workFile == "" && !merged && !*noWalkUp {
+		// Like the go command, treat a package directory as part of the
+		// nearest module above it.
+		if root, ok := deptree.FindParentModule(cwd); ok {
+			fmt.Fprintln(os.Stderr, "Using module root "+root)
+			cwd = root
+			modFile = path.Join(cwd, "go.mod")
+		}
+	}
+	if _, err := os.Stat(modFile); os.IsNotExist(err) && !*recursive && workFile == "" && !merged {
+		println("ERROR: go.mod is not present in this directory, please only run this tool in the root of your go project or specify a path to the root directory of a go project")
+		os.Exit(1)
 	}
-	modFilePath := path.Join(rawPath, "go.mod")
-	fileBytes, err := ioutil.ReadFile(modFilePath)
 
+	out, closeOutput, err := openOutput(*output, *tee)
 	if err != nil {
-		fmt.Println(indent + strings.Split(modPath, " //")[0])
-		return
+		log.Println(err)
+		os.Exit(1)
 	}
 
-	found := false
-
-	lines := strings.Split(string(fileBytes), "\n")
-	fmt.Println(indent + strings.Split(modPath, " //")[0] + ":")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if !found {
-			if line == "require (" {
-				found = true
+	if *recursive || merged {
+		if *format != "text" && *format != "json" {
+			fmt.Println("Invalid value supplied for format, -recursive only supports text or json")
+			os.Exit(1)
+		}
+		list := listRecursive
+		if merged {
+			list = func(dir string, opts ...deptree.Option) (map[string]*module, []string, error) {
+				return listRoots(base, roots, *recursive, opts...)
+			}
+		}
+		graphs, keys, err := list(cwd, options...)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if *showLicenses || *allowedLicenses != "" {
+			for _, key := range keys {
+				graphs[key].readLicenses()
+			}
+		}
+		if *verify {
+			for _, key := range keys {
+				if err := graphs[key].verifySums(*verifyHashes, *jobs); err != nil {
+					log.Println(err)
+					os.Exit(1)
+				}
+			}
+		}
+		if *outdated {
+			for _, key := range keys {
+				if err := graphs[key].readOutdated(*jobs); err != nil {
+					log.Println(err)
+					os.Exit(1)
+				}
+			}
+		}
+		if *vuln {
+			for _, key := range keys {
+				if err := graphs[key].readVulnerabilities(*vulnURL); err != nil {
+					log.Println(err)
+					os.Exit(1)
+				}
 			}
-		} else {
-			if line == "" {
-				// skip
-			} else if line == ")" {
-				return
-			} else {
-				getModuleList(line, indent+"  ", depth-1)
+		}
+		write := writeRecursive
+		if merged {
+			write = writeMerged
+		}
+		if err := write(out, *format, graphs, keys, *maxDepth); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if err := closeOutput(); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		passed := true
+		for _, key := range keys {
+			if !checkTree(os.Stderr, key+": ", graphs[key]) {
+				passed = false
 			}
 		}
+		if !passed {
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
-}
 
-func getSemVer(version string) string {
-	re := regexp.MustCompile("(v\\d+\\.\\d+\\.\\d+)(-.*)*")
-	match := re.FindStringSubmatch(version)
-	if len(match) == 0 {
-		return ""
-	} else if len(match) == 1 {
-		return match[0]
+	if _, err := os.Stat(path.Join(cwd, "vendor", "modules.txt")); (*vendor || err == nil) && !*noVendor && workFile == "" {
+		options = append(options, deptree.WithVendor(true))
+	}
+	// Stream the graph as it's walked when it's written out whole, as it is
+	// built, rather than searched, served or rewritten first.
+	if *format == "ndjson" && *searchText == "" && *rdeps == "" && *explainUnknown == "" &&
+		*serve == "" && *groupOutput == "" && *redact == "" && *prefix == "" && !*onlyFirstPartyEdges &&
+		*granularity != "package" {
+		ndjsonStream = newNDJSONWriter(out)
+		options = append(options, deptree.WithVisit(ndjsonStream.visit))
+	}
+	var graph *deptree.Graph
+	if workFile != "" {
+		graph, err = deptree.BuildWorkspace(workFile, options...)
+	} else {
+		graph, err = deptree.Build(cwd, options...)
+	}
+	if err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	m := newModule(graph)
+	if *packages {
+		if err := m.readPackageImports(*packageDeps); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+	}
+	if *showLicenses || *allowedLicenses != "" {
+		m.readLicenses()
+	}
+	if *verify {
+		if err := m.verifySums(*verifyHashes, *jobs); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+	}
+	if *outdated {
+		if err := m.readOutdated(*jobs); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+	}
+	if *vuln {
+		if err := m.readVulnerabilities(*vulnURL); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if *searchText != "" {
+		paths, truncated := m.shortestPaths(*searchText), false
+		if *allPaths {
+			paths, truncated = m.allPaths(*searchText, *maxPaths)
+		}
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "Unable to find module '"+*searchText+"' in dependency tree.")
+			os.Exit(1)
+		}
+		if truncated {
+			fmt.Fprintf(os.Stderr, "Only printing the first %d paths, raise -maxPaths to see more.\n", *maxPaths)
+		}
+		if err := writePaths(out, m, paths); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if err := closeOutput(); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
-	return match[1]
-}
 
-func getModuleName(cwd string) string {
+	if *rdeps != "" && *transitive {
+		dependents := m.transitiveDependents(*rdeps)
+		if dependents.Count == 0 {
+			fmt.Fprintln(os.Stderr, "Nothing in the dependency tree requires '"+*rdeps+"'.")
+			os.Exit(1)
+		}
+		if err := writeDependents(out, *format, dependents); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if err := closeOutput(); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
-	modFilePath := path.Join(cwd, "go.mod")
-	fileBytes, err := ioutil.ReadFile(modFilePath)
+	if *rdeps != "" {
+		requiredBy := m.reverseDependencies(*rdeps)
+		if len(requiredBy) == 0 {
+			fmt.Fprintln(os.Stderr, "Nothing in the dependency tree requires '"+*rdeps+"'.")
+			os.Exit(1)
+		}
+		if err := writeReverseDependencies(out, *format, requiredBy); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		if err := closeOutput(); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
 
-	if err != nil {
-		fmt.Println("Error reading go.mod: ", err)
-		os.Exit(1)
+	if *explainUnknown != "" {
+		explainResolution(out, m, *explainUnknown)
+		if err := closeOutput(); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		os.Exit(0)
 	}
 
-	lines := strings.Split(string(fileBytes), "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "module ") {
-			modAddress := strings.Split(line, "module ")[1]
-			if strings.Contains(modAddress, "\"") {
-				modAddress = modAddress[1 : len(modAddress)-1]
-			}
-			var modName string
-			if strings.HasSuffix(cwd, modAddress) {
-				modName = modAddress
-			} else {
-				modName = modAddress + strings.Split(cwd, modAddress)[1]
-			}
-			return modName
+	view := m
+	if *granularity == "package" {
+		view = m.packageGraph()
+	}
+	if *prefix != "" {
+		prefixes := strings.Split(*prefix, ",")
+		whole := view
+		view = whole.filtered(func(i int) bool { return whole.hasPathPrefix(i, prefixes) })
+	}
+	if *onlyFirstPartyEdges {
+		view = view.firstPartyGraph(strings.Split(*firstParty, ","))
+	}
+	if *redact != "" {
+		view = view.redacted(redactor{prefixes: strings.Split(*redact, ","), versions: *redactVersions})
+	}
+
+	if *serve != "" {
+		fmt.Fprintln(os.Stderr, "Serving the dependency tree at http://"+*serve)
+		if err := http.ListenAndServe(*serve, newServer(view)); err != nil {
+			log.Println(err)
+			os.Exit(1)
 		}
 	}
 
-	fmt.Println("Invalid go.mod, not module name")
-	os.Exit(1)
-	return ""
-}
+	if *groupOutput != "" {
+		// Each file is meant to be read by other tools, so they're JSON
+		// unless another format was asked for.
+		groupFormat := "json"
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "format" {
+				groupFormat = *format
+			}
+		})
+		if err := writeGroups(*groupOutput, groupFormat, view, *maxDepth); err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+	} else if err := writeGraph(out, *format, view, *maxDepth); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
+	if err := closeOutput(); err != nil {
+		log.Println(err)
+		os.Exit(1)
+	}
 
-func escapeCapitalsInModuleName(name string) string {
-	letters := strings.Split(name, "")
-	newName := ""
-	for _, letter := range letters {
-		if strings.ToLower(letter) != letter {
-			newName += "!" + strings.ToLower(letter)
-		} else {
-			newName += letter
+	if *dtrackURL != "" {
+		apiKey := *dtrackAPIKey
+		if apiKey == "" {
+			apiKey = os.Getenv("DTRACK_API_KEY")
+		}
+		if err := uploadBOM(*dtrackURL, apiKey, m); err != nil {
+			log.Println(err)
+			os.Exit(1)
 		}
 	}
-	return newName
+
+	if !checkTree(os.Stderr, "", m) {
+		os.Exit(1)
+	}
+
+	os.Exit(0)
 }
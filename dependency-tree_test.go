@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+)
+
+func TestVanityPaths(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/vanity", "-requireCleanTree")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `example.com/vanity:
+  gopkg.in/Bar.v1 v1.0.0:
+  gopkg.in/Foo.v2 v2.1.0:
+    gopkg.in/yaml.v3 v3.0.1:
+  gopkg.in/yaml.v3 v3.0.1:
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestModulePath(t *testing.T) {
+	clean := path.Join(fixtureGopath(t), "src", "example.com", "clean")
+	want := `example.com/clean:
+  example.com/c v1.0.0:
+`
+	tests := []struct {
+		name       string
+		modulePath string
+	}{
+		{name: "directory", modulePath: clean},
+		{name: "go.mod", modulePath: path.Join(clean, "go.mod")},
+		{name: "relative directory", modulePath: "../clean"},
+		{name: "relative go.mod", modulePath: "../clean/go.mod"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout, stderr, code := run(t, "example.com/app", "-modulePath", test.modulePath)
+			if code != 0 {
+				t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+			}
+			if stdout != want {
+				t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+			}
+		})
+	}
+}
+
+func TestGopathList(t *testing.T) {
+	gopath := strings.Join([]string{t.TempDir(), fixtureGopath(t)}, string(os.PathListSeparator))
+	stdout, stderr, code := runEnv(t, []string{"GOPATH=" + gopath, "GOMODCACHE="}, "example.com/clean", "-noGoEnv", "-requireCleanTree")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `example.com/clean:
+  example.com/c v1.0.0:
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+)
+
+// nodeLabel returns the label of the module at index i in the graph formats,
+// which is its name followed with -showFanout by its number of requires and
+// with -licenses by its license.
+func (m *module) nodeLabel(i int) string {
+	if *showFanout {
+		return fmt.Sprintf("%s (%d)%s", m.Indexes[i], len(m.Packages[i]), m.licenseSuffix(i))
+	}
+	return m.Indexes[i] + m.licenseSuffix(i)
+}
+
+// writeDOT writes the graph as a Graphviz digraph, labelling each node with
+// its module name, drawing the root bold and unknown modules dashed. With
+// -weights each edge is labelled with, and drawn in proportion to, the number
+// of modules reachable through it.
+func writeDOT(w io.Writer, m *module) error {
+	if _, err := fmt.Fprintln(w, "digraph {"); err != nil {
+		return err
+	}
+	for i := range m.Indexes {
+		// Unknown modules are dashed to show where resolution fell off.
+		style := ""
+		if i == 0 {
+			style = ", style=bold"
+		} else if _, ok := m.Unknown[i]; ok {
+			style = ", style=dashed"
+		}
+		if _, err := fmt.Fprintf(w, "  %d [label=%s%s];\n", i, strconv.Quote(m.nodeLabel(i)), style); err != nil {
+			return err
+		}
+	}
+	var sizes []int
+	if *weights {
+		sizes = m.subtreeSizes()
+	}
+	for i := range m.Indexes {
+		for _, child := range m.Packages[i] {
+			attributes := ""
+			if *weights {
+				attributes = fmt.Sprintf(" [label=\"%d\", penwidth=%.2f]", sizes[child], edgeWidth(sizes[child]))
+			}
+			if _, err := fmt.Fprintf(w, "  %d -> %d%s;\n", i, child, attributes); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+// writeTerraformDOT writes the graph in the DOT dialect produced by
+// terraform graph, so it can be consumed by tooling built around that output:
+// tab indentation, a "root" subgraph, "[root] "-prefixed quoted node ids and
+// quoted attribute values.
+func writeTerraformDOT(w io.Writer, m *module) error {
+	header := "digraph {\n\tcompound = \"true\"\n\tnewrank = \"true\"\n\trankdir = \"RL\"\n\tsubgraph \"root\" {\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for i, name := range m.Indexes {
+		style := ""
+		if _, ok := m.Unknown[i]; ok {
+			style = ", style = \"dashed\""
+		}
+		if _, err := fmt.Fprintf(w, "\t\t%s [label = %s, shape = \"box\"%s]\n", strconv.Quote("[root] "+name), strconv.Quote(m.nodeLabel(i)), style); err != nil {
+			return err
+		}
+	}
+	for i, name := range m.Indexes {
+		for _, child := range m.Packages[i] {
+			if _, err := fmt.Fprintf(w, "\t\t%s -> %s\n", strconv.Quote("[root] "+name), strconv.Quote("[root] "+m.Indexes[child])); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, "\t}\n}\n")
+	return err
+}
+
+// writeSVG renders the graph to SVG by piping its DOT representation through
+// the Graphviz dot binary.
+func writeSVG(w io.Writer, m *module) error {
+	dotPath, err := exec.LookPath("dot")
+	if err != nil {
+		return errors.New("the svg format needs the Graphviz dot binary on your PATH, please install Graphviz or use -format=dot")
+	}
+
+	var graph bytes.Buffer
+	if err := writeDOT(&graph, m); err != nil {
+		return err
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(dotPath, "-Tsvg")
+	cmd.Stdin = &graph
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("running dot: %v: %s", err, stderr.String())
+	}
+	return nil
+}
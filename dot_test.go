@@ -0,0 +1,97 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureDOT is the DOT graph of example.com/app.
+const fixtureDOT = `digraph {
+  0 [label="example.com/app", style=bold];
+  1 [label="example.com/a v1.0.0"];
+  2 [label="example.com/b v1.0.0"];
+  3 [label="example.com/c v1.0.0"];
+  4 [label="example.com/b v1.1.0"];
+  5 [label="example.com/missing v1.0.0", style=dashed];
+  0 -> 1;
+  0 -> 4;
+  0 -> 5;
+  1 -> 2;
+  1 -> 3;
+  2 -> 3;
+  4 -> 3;
+}
+`
+
+func TestDOT(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "dot")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	if stdout != fixtureDOT {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, fixtureDOT)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	// A dot that wraps its input in an svg element stands in for Graphviz,
+	// showing the DOT graph is what gets rendered.
+	bin := t.TempDir()
+	script := "#!/bin/sh\n[ \"$1\" = -Tsvg ] || exit 2\necho '<svg>'\nwhile IFS= read -r line; do echo \"$line\"; done\necho '</svg>'\n"
+	if err := ioutil.WriteFile(filepath.Join(bin, "dot"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr, code := runEnv(t, []string{"PATH=" + bin}, "example.com/app", "-format", "svg")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	if want := "<svg>\n" + fixtureDOT + "</svg>\n"; stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestSVGWithoutGraphviz(t *testing.T) {
+	stdout, stderr, code := runEnv(t, []string{"PATH=" + t.TempDir()}, "example.com/app", "-format", "svg")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if stdout != "" {
+		t.Errorf("output = %q, want nothing", stdout)
+	}
+	if !strings.Contains(stderr, "needs the Graphviz dot binary") {
+		t.Errorf("stderr = %q, want it to say Graphviz is needed", stderr)
+	}
+}
+
+func TestTerraformDOT(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "tf-dot")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `digraph {
+	compound = "true"
+	newrank = "true"
+	rankdir = "RL"
+	subgraph "root" {
+		"[root] example.com/app" [label = "example.com/app", shape = "box"]
+		"[root] example.com/a v1.0.0" [label = "example.com/a v1.0.0", shape = "box"]
+		"[root] example.com/b v1.0.0" [label = "example.com/b v1.0.0", shape = "box"]
+		"[root] example.com/c v1.0.0" [label = "example.com/c v1.0.0", shape = "box"]
+		"[root] example.com/b v1.1.0" [label = "example.com/b v1.1.0", shape = "box"]
+		"[root] example.com/missing v1.0.0" [label = "example.com/missing v1.0.0", shape = "box", style = "dashed"]
+		"[root] example.com/app" -> "[root] example.com/a v1.0.0"
+		"[root] example.com/app" -> "[root] example.com/b v1.1.0"
+		"[root] example.com/app" -> "[root] example.com/missing v1.0.0"
+		"[root] example.com/a v1.0.0" -> "[root] example.com/b v1.0.0"
+		"[root] example.com/a v1.0.0" -> "[root] example.com/c v1.0.0"
+		"[root] example.com/b v1.0.0" -> "[root] example.com/c v1.0.0"
+		"[root] example.com/b v1.1.0" -> "[root] example.com/c v1.0.0"
+	}
+}
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
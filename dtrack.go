@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// cycloneDXBOM is the subset of a CycloneDX 1.4 document that
+// OWASP Dependency-Track reads when ingesting a project's components.
+type cycloneDXBOM struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cycloneDXMetadata     `json:"metadata"`
+	Components   []cycloneDXComponent  `json:"components"`
+	Dependencies []cycloneDXDependency `json:"dependencies"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Component cycloneDXComponent `json:"component"`
+}
+
+type cycloneDXComponent struct {
+	Type     string             `json:"type"`
+	BOMRef   string             `json:"bom-ref"`
+	Name     string             `json:"name"`
+	Version  string             `json:"version,omitempty"`
+	PURL     string             `json:"purl"`
+	Licenses []cycloneDXLicense `json:"licenses,omitempty"`
+	// Properties carries the go.sum hash, which is a hash of the module's
+	// file tree rather than of any one artifact, so isn't a CycloneDX hash.
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXLicense struct {
+	License struct {
+		ID string `json:"id"`
+	} `json:"license"`
+}
+
+type cycloneDXDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+// purl returns the package URL of a module name as stored in Indexes.
+func purl(name string) string {
+	modPath, version := deptree.SplitModuleName(name)
+	if version == "" {
+		return "pkg:golang/" + modPath
+	}
+	return "pkg:golang/" + modPath + "@" + version
+}
+
+// readGoSumH1 reads the h1 hashes of module sources from the go.sum in dir,
+// as go.sum writes them, keyed by module name as stored in Indexes.
+func readGoSumH1(dir string) map[string]string {
+	sums := make(map[string]string)
+	fileBytes, err := ioutil.ReadFile(path.Join(dir, "go.sum"))
+	if err != nil {
+		return sums
+	}
+	for _, line := range strings.Split(string(fileBytes), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") || !strings.HasPrefix(fields[2], "h1:") {
+			continue
+		}
+		sums[fields[0]+" "+fields[1]] = fields[2]
+	}
+	return sums
+}
+
+// randomUUID returns a random version 4 UUID.
+func randomUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// buildBOM builds a CycloneDX document of the graph, with the root module as
+// the project the BOM describes.
+func buildBOM(m *module) (cycloneDXBOM, error) {
+	serial, err := randomUUID()
+	if err != nil {
+		return cycloneDXBOM{}, err
+	}
+	sums := readGoSumH1(m.Dir)
+
+	bom := cycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.4",
+		SerialNumber: "urn:uuid:" + serial,
+		Version:      1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Component: cycloneDXComponent{
+				Type:   "application",
+				BOMRef: purl(m.Indexes[0]),
+				Name:   m.Indexes[0],
+				PURL:   purl(m.Indexes[0]),
+			},
+		},
+		Components:   make([]cycloneDXComponent, 0, len(m.Indexes)),
+		Dependencies: make([]cycloneDXDependency, 0, len(m.Indexes)),
+	}
+	for i, name := range m.Indexes {
+		if i != 0 {
+			modPath, version := deptree.SplitModuleName(name)
+			component := cycloneDXComponent{
+				Type:    "library",
+				BOMRef:  purl(name),
+				Name:    modPath,
+				Version: version,
+				PURL:    purl(name),
+			}
+			if license, ok := m.licenses[i]; ok && license != unknownLicense {
+				var l cycloneDXLicense
+				l.License.ID = license
+				component.Licenses = []cycloneDXLicense{l}
+			}
+			if hash, ok := sums[name]; ok {
+				component.Properties = []cycloneDXProperty{{Name: "go.sum h1", Value: hash}}
+			}
+			bom.Components = append(bom.Components, component)
+		}
+
+		dependsOn := make([]string, 0, len(m.Packages[i]))
+		for _, child := range m.Packages[i] {
+			dependsOn = append(dependsOn, purl(m.Indexes[child]))
+		}
+		bom.Dependencies = append(bom.Dependencies, cycloneDXDependency{
+			Ref:       purl(name),
+			DependsOn: dependsOn,
+		})
+	}
+	return bom, nil
+}
+
+// writeDependencyTrack writes the graph as a CycloneDX document ready for
+// OWASP Dependency-Track.
+func writeDependencyTrack(w io.Writer, m *module) error {
+	bom, err := buildBOM(m)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(bom, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// uploadBOM uploads a CycloneDX document of the graph to the Dependency-Track
+// server at baseURL, creating the project named after the root module if it
+// doesn't exist yet.
+func uploadBOM(baseURL, apiKey string, m *module) error {
+	bom, err := buildBOM(m)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(bom)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"projectName", m.Indexes[0]},
+		{"projectVersion", *dtrackProjectVersion},
+		{"autoCreate", "true"},
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	part, err := form.CreateFormFile("bom", "bom.json")
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(b); err != nil {
+		return err
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(baseURL, "/")+"/api/v1/bom", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("X-Api-Key", apiKey)
+
+	client := &http.Client{Timeout: time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("uploading BOM to Dependency-Track: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
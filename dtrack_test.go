@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// wantComponents is the components of the BOM of example.com/clean.
+var wantComponents = []cycloneDXComponent{
+	{
+		Type:    "library",
+		BOMRef:  "pkg:golang/example.com/c@v1.0.0",
+		Name:    "example.com/c",
+		Version: "v1.0.0",
+		PURL:    "pkg:golang/example.com/c@v1.0.0",
+		Properties: []cycloneDXProperty{
+			{Name: "go.sum h1", Value: "h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU="},
+		},
+	},
+}
+
+// checkBOM checks b is the BOM of example.com/clean.
+func checkBOM(t *testing.T, b []byte) {
+	t.Helper()
+	var bom cycloneDXBOM
+	if err := json.Unmarshal(b, &bom); err != nil {
+		t.Fatalf("BOM isn't JSON: %v\n%s", err, b)
+	}
+	if bom.BOMFormat != "CycloneDX" || bom.SpecVersion != "1.4" || !strings.HasPrefix(bom.SerialNumber, "urn:uuid:") {
+		t.Errorf("BOM header = %q %q %q, want a CycloneDX 1.4 document with a UUID serial number", bom.BOMFormat, bom.SpecVersion, bom.SerialNumber)
+	}
+	if bom.Metadata.Component.PURL != "pkg:golang/example.com/clean" {
+		t.Errorf("BOM describes %q, want pkg:golang/example.com/clean", bom.Metadata.Component.PURL)
+	}
+	if !reflect.DeepEqual(bom.Components, wantComponents) {
+		t.Errorf("components = %+v, want %+v", bom.Components, wantComponents)
+	}
+	wantDependencies := []cycloneDXDependency{
+		{Ref: "pkg:golang/example.com/clean", DependsOn: []string{"pkg:golang/example.com/c@v1.0.0"}},
+		{Ref: "pkg:golang/example.com/c@v1.0.0", DependsOn: []string{}},
+	}
+	if !reflect.DeepEqual(bom.Dependencies, wantDependencies) {
+		t.Errorf("dependencies = %+v, want %+v", bom.Dependencies, wantDependencies)
+	}
+}
+
+func TestDependencyTrack(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/clean", "-format", "dependency-track")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	checkBOM(t, []byte(stdout))
+}
+
+func TestCycloneDX(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/clean", "-format", "cyclonedx")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	checkBOM(t, []byte(stdout))
+}
+
+func TestDependencyTrackUpload(t *testing.T) {
+	var (
+		apiKey string
+		fields = make(map[string]string)
+		bom    []byte
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/bom" {
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		apiKey = r.Header.Get("X-Api-Key")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for name, values := range r.MultipartForm.Value {
+			fields[name] = values[0]
+		}
+		file, _, err := r.FormFile("bom")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		bom, _ = ioutil.ReadAll(file)
+	}))
+	defer server.Close()
+
+	_, stderr, code := runEnv(t, []string{"DTRACK_API_KEY=secret"}, "example.com/clean",
+		"-dtrackUrl", server.URL+"/", "-dtrackProjectVersion", "1.2.3")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	if apiKey != "secret" {
+		t.Errorf("API key = %q, want the one from DTRACK_API_KEY", apiKey)
+	}
+	wantFields := map[string]string{
+		"projectName":    "example.com/clean",
+		"projectVersion": "1.2.3",
+		"autoCreate":     "true",
+	}
+	if !reflect.DeepEqual(fields, wantFields) {
+		t.Errorf("form fields = %q, want %q", fields, wantFields)
+	}
+	checkBOM(t, bom)
+}
+
+func TestDependencyTrackUploadRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid API key", http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	_, stderr, code := run(t, "example.com/clean", "-dtrackUrl", server.URL, "-dtrackApiKey", "wrong")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if want := "uploading BOM to Dependency-Track: 401 Unauthorized: invalid API key"; !strings.Contains(stderr, want) {
+		t.Errorf("stderr = %q, want it to contain %q", stderr, want)
+	}
+}
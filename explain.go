@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// explainResolution writes how each module in the graph matching target, a
+// module path optionally followed by a version, was resolved: the settings
+// used, every directory tried and why each one failed. A target that isn't in
+// the graph is explained as if it had been required.
+func explainResolution(w io.Writer, m *module, target string) {
+	targetPath, targetVersion := deptree.NameAndVersion(target)
+	names := make([]string, 0)
+	for i, name := range m.Indexes {
+		modPath, version := deptree.SplitModuleName(name)
+		if i != 0 && modPath == targetPath && (targetVersion == "" || version == targetVersion) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Fprintln(w, target+" isn't in the dependency tree, explaining it as if it were required")
+		names = append(names, strings.TrimSpace(targetPath+" "+targetVersion))
+	}
+
+	for _, name := range names {
+		escaped := deptree.EscapeCapitals(name)
+		fmt.Fprintln(w, name+":")
+		fmt.Fprintln(w, "  GOPATH: "+m.Options().Gopath)
+		fmt.Fprintln(w, "  GOMODCACHE: "+m.Options().ModCache)
+		if m.Options().Proxy != "" {
+			fmt.Fprintln(w, "  GOPROXY, tried last: "+m.Options().Proxy)
+		}
+		fmt.Fprintln(w, "  Escaped path: "+escaped)
+		if i, ok := m.Lookup[name]; ok {
+			if _, unknown := m.Unknown[i]; unknown {
+				fmt.Fprintln(w, "  Status: unknown")
+			} else if err, failed := m.Errors[i]; failed {
+				fmt.Fprintln(w, "  Status: found but unreadable: "+err.Error())
+			} else if _, walked := m.Packages[i]; walked {
+				fmt.Fprintln(w, "  Status: resolved")
+			} else {
+				fmt.Fprintln(w, "  Status: not walked, beyond -maxDepth")
+			}
+		}
+		if r, ok := m.Replacement(name); ok {
+			if r.IsLocal() {
+				fmt.Fprintln(w, "  Replaced by local directory: "+r.LocalDir(m.Dir))
+				continue
+			}
+			fmt.Fprintln(w, "  Replaced by: "+r.New+" "+r.NewVersion)
+			escaped = deptree.EscapeCapitals(r.New + " " + r.NewVersion)
+		}
+		fmt.Fprintln(w, "  Candidates:")
+		for _, candidate := range m.Options().ResolutionCandidates(escaped) {
+			result := "found"
+			if _, err := os.Stat(candidate); err != nil {
+				result = err.Error()
+			} else if _, err := os.Stat(path.Join(candidate, "go.mod")); err != nil {
+				result = "found, but " + err.Error()
+			}
+			fmt.Fprintln(w, "    "+candidate+": "+result)
+		}
+	}
+}
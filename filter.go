@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// hasPathPrefix reports whether the module at index i has a path starting
+// with one of the given prefixes.
+func (m *module) hasPathPrefix(i int, prefixes []string) bool {
+	modPath, _ := deptree.SplitModuleName(m.Indexes[i])
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(modPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// filtered returns a new graph holding only the modules keep reports true for
+// and the requires between them. The root is always kept, and any module the
+// root no longer reaches once the others are dropped is pruned as well, so the
+// new graph's indexes are all reachable from its root and are numbered in the
+// order they're found.
+func (m *module) filtered(keep func(i int) bool) *module {
+	sub := newModule(deptree.NewGraph(deptree.WithOptions(m.Options())))
+	sub.Dir = m.Dir
+	sub.RootRequires = m.RootRequires
+	sub.Replaces = m.Replaces
+
+	queue := []int{0}
+	sub.Index(m.Indexes[0])
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		children, ok := m.Packages[current]
+		if !ok {
+			continue
+		}
+		subCurrent := sub.Lookup[m.Indexes[current]]
+		subChildren := make([]int, 0, len(children))
+		for _, child := range children {
+			if !keep(child) {
+				continue
+			}
+			if _, seen := sub.Lookup[m.Indexes[child]]; !seen {
+				queue = append(queue, child)
+			}
+			subChild := sub.Index(m.Indexes[child])
+			if _, ok := m.Indirect[deptree.Edge{From: current, To: child}]; ok {
+				sub.Indirect[deptree.Edge{From: subCurrent, To: subChild}] = struct{}{}
+			}
+			subChildren = append(subChildren, subChild)
+		}
+		sub.Packages[subCurrent] = subChildren
+	}
+
+	sub.copyModuleState(m)
+	return sub
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestPrefix(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-prefix", "example.com/b,example.com/c", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var got jsonGraph
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, stdout)
+	}
+	// example.com/b v1.0.0 is only required by example.com/a, which is
+	// filtered out, so it's pruned along with it.
+	wantIndexes := []string{
+		"example.com/app",
+		"example.com/b v1.1.0",
+		"example.com/c v1.0.0",
+	}
+	if !reflect.DeepEqual(got.Indexes, wantIndexes) {
+		t.Errorf("indexes = %q, want %q", got.Indexes, wantIndexes)
+	}
+	if want := map[int][]int{0: {1}, 1: {2}, 2: {}}; !reflect.DeepEqual(got.Packages, want) {
+		t.Errorf("packages = %v, want %v", got.Packages, want)
+	}
+}
+
+func TestFilteredLeavesNoDanglingIndexes(t *testing.T) {
+	m := newModule(deptree.NewGraph())
+	for _, name := range []string{
+		"example.com/app",
+		"example.com/x v1.0.0",
+		"example.com/a v1.0.0",
+		"example.com/b v1.0.0",
+		"example.com/a/sub v1.0.0",
+	} {
+		m.Index(name)
+	}
+	// a/sub is only reachable through x, which the filter drops.
+	m.Packages[0] = []int{1, 2}
+	m.Packages[1] = []int{4, 3}
+	m.Packages[2] = []int{3}
+	m.Packages[3] = []int{}
+	m.Packages[4] = []int{}
+	m.Indirect[deptree.Edge{From: 2, To: 3}] = struct{}{}
+	m.Unknown[3] = struct{}{}
+
+	prefixes := []string{"example.com/a", "example.com/b"}
+	sub := m.filtered(func(i int) bool { return m.hasPathPrefix(i, prefixes) })
+
+	if want := []string{"example.com/app", "example.com/a v1.0.0", "example.com/b v1.0.0"}; !reflect.DeepEqual(sub.Indexes, want) {
+		t.Fatalf("indexes = %q, want %q", sub.Indexes, want)
+	}
+	reached := map[int]bool{0: true}
+	queue := []int{0}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, child := range sub.Packages[current] {
+			if child < 0 || child >= len(sub.Indexes) {
+				t.Fatalf("packages[%d] refers to index %d, outside of %d indexes", current, child, len(sub.Indexes))
+			}
+			if !reached[child] {
+				reached[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+	for i, name := range sub.Indexes {
+		if !reached[i] {
+			t.Errorf("index %d (%s) isn't reachable from the root", i, name)
+		}
+	}
+	for from := range sub.Packages {
+		if from < 0 || from >= len(sub.Indexes) {
+			t.Errorf("packages has an entry for index %d, outside of %d indexes", from, len(sub.Indexes))
+		}
+	}
+	if _, ok := sub.Indirect[deptree.Edge{From: 1, To: 2}]; !ok || len(sub.Indirect) != 1 {
+		t.Errorf("indirect = %v, want only the a -> b edge", sub.Indirect)
+	}
+	if _, ok := sub.Unknown[2]; !ok || len(sub.Unknown) != 1 {
+		t.Errorf("unknown = %v, want only b", sub.Unknown)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// targetMatcher returns whether the module at an index matches target, a
+// module path optionally followed by a version. The root never matches.
+func (m *module) targetMatcher(target string) func(i int) bool {
+	targetPath, targetVersion := deptree.NameAndVersion(target)
+	return func(i int) bool {
+		modPath, version := deptree.SplitModuleName(m.Indexes[i])
+		return i != 0 && modPath == targetPath && (targetVersion == "" || version == targetVersion)
+	}
+}
+
+// shortestPaths returns every shortest path from the root to a module
+// matching target, a module path optionally followed by a version. Each path
+// is the list of indexes from the root to the match.
+func (m *module) shortestPaths(target string) [][]int {
+	matches := m.targetMatcher(target)
+
+	// Walk breadth first from the root, recording every parent a module is
+	// reached from at its shortest distance, and stop at the first level
+	// holding a match.
+	levels := m.WalkLevels(matches)
+	parents := levels.Parents
+	found := make([]int, 0)
+	for _, i := range levels.Order {
+		if matches(i) {
+			found = append(found, i)
+		}
+	}
+
+	paths := make([][]int, 0)
+	var walkBack func(i int, suffix []int)
+	walkBack = func(i int, suffix []int) {
+		suffix = append([]int{i}, suffix...)
+		if i == 0 {
+			paths = append(paths, suffix)
+			return
+		}
+		for _, parent := range parents[i] {
+			walkBack(parent, suffix)
+		}
+	}
+	for _, i := range found {
+		walkBack(i, nil)
+	}
+	return paths
+}
+
+// allPaths returns up to limit paths from the root to a module matching
+// target, of any length, in the order the tree lists them. A path never
+// visits a module twice, so cycles don't repeat. It also reports whether
+// there were more paths than limit.
+func (m *module) allPaths(target string, limit int) ([][]int, bool) {
+	matches := m.targetMatcher(target)
+
+	// Only modules some match can be reached from are worth walking into,
+	// which keeps the search from wandering the rest of the tree.
+	requiredBy := make(map[int][]int)
+	for parent, children := range m.Packages {
+		for _, child := range children {
+			requiredBy[child] = append(requiredBy[child], parent)
+		}
+	}
+	leadsToMatch := make(map[int]bool)
+	queue := make([]int, 0)
+	for i := range m.Indexes {
+		if matches(i) {
+			leadsToMatch[i] = true
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, parent := range requiredBy[i] {
+			if !leadsToMatch[parent] {
+				leadsToMatch[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	paths := make([][]int, 0)
+	truncated := false
+	onPath := make(map[int]bool)
+	var walk func(i int, prefix []int)
+	walk = func(i int, prefix []int) {
+		if truncated || onPath[i] || !leadsToMatch[i] {
+			return
+		}
+		path := append(append([]int{}, prefix...), i)
+		if matches(i) {
+			if len(paths) == limit {
+				truncated = true
+				return
+			}
+			paths = append(paths, path)
+		}
+		onPath[i] = true
+		for _, child := range m.Packages[i] {
+			walk(child, path)
+		}
+		delete(onPath, i)
+	}
+	walk(0, nil)
+	return paths, truncated
+}
+
+// writePaths writes each path one hop per line, indenting each hop under the
+// module requiring it, with a blank line between paths.
+func writePaths(w io.Writer, m *module, paths [][]int) error {
+	for n, p := range paths {
+		if n > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		for depth, i := range p {
+			if _, err := fmt.Fprintln(w, strings.Repeat(*indentText, depth)+m.Indexes[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package main
+
+// firstPartyGraph returns a new graph holding only the first party modules,
+// those with a path starting with one of the given prefixes, and the requires
+// between them, dropping every third party module along with its edges. The
+// root is always kept so the graph still has one.
+func (m *module) firstPartyGraph(prefixes []string) *module {
+	return m.filtered(func(i int) bool { return m.hasPathPrefix(i, prefixes) })
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestOnlyFirstPartyEdges(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-firstParty", "example.com/a,example.com/c", "-onlyFirstPartyEdges")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	// example.com/c is also required by both versions of example.com/b, but
+	// only the edge from example.com/a is between first party modules.
+	want := `example.com/app:
+  example.com/a v1.0.0:
+    example.com/c v1.0.0:
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestOnlyFirstPartyEdgesRequiresFirstParty(t *testing.T) {
+	stdout, _, code := run(t, "example.com/app", "-onlyFirstPartyEdges")
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if want := "Invalid value supplied for onlyFirstPartyEdges, -onlyFirstPartyEdges requires -firstParty\n"; stdout != want {
+		t.Errorf("output = %q, want %q", stdout, want)
+	}
+}
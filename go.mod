@@ -1,3 +1,5 @@
 module github.com/kapilpau/go-mod-dependency-tree
 
 go 1.15
+
+require golang.org/x/mod v0.4.2
@@ -0,0 +1,43 @@
+package main
+
+import "github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+
+// module is the dependency graph being reported on, along with the state the
+// reports add to it.
+type module struct {
+	*deptree.Graph
+
+	imports []packageImport
+	// rootPackages holds the root module's packages, and skippedFiles the
+	// Go files whose imports couldn't be read, when imports are read.
+	rootPackages []string
+	skippedFiles []skippedFile
+	vulns        []vulnerability
+	// outdated holds the modules with newer versions on the module proxy,
+	// nil unless they were checked.
+	outdated []outdatedModule
+	// sums is the result of checking the graph against go.sum, nil unless
+	// it was checked.
+	sums *sumVerification
+	// resolved holds the version minimal version selection picks for every
+	// module path, nil until it's worked out.
+	resolved []resolvedVersion
+	// licenses holds the SPDX identifier of each module's license, nil
+	// unless they were read.
+	licenses map[int]string
+
+	onStack       map[int]bool
+	subtrees      map[int]int
+	subtreeDepths map[int]int
+}
+
+// newModule returns the graph g ready to report on.
+func newModule(g *deptree.Graph) *module {
+	return &module{
+		Graph: g,
+
+		onStack:       make(map[int]bool),
+		subtrees:      make(map[int]int),
+		subtreeDepths: make(map[int]int),
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestJobs(t *testing.T) {
+	serial, stderr, code := run(t, "example.com/app", "-jobs", "1", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	for i := 0; i < 5; i++ {
+		parallel, stderr, code := run(t, "example.com/app", "-jobs", "8", "-format", "json")
+		if code != 0 {
+			t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+		}
+		if parallel != serial {
+			t.Fatalf("output with 8 jobs =\n%s\nwant the same as with 1 job\n%s", parallel, serial)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+	gomodule "golang.org/x/mod/module"
+)
+
+var unsafeFileChars = regexp.MustCompile(`[^a-z0-9._!-]+`)
+
+// subgraph returns a new graph holding only the modules reachable from the
+// module at index i, which becomes the root of the new graph.
+func (m *module) subgraph(i int) *module {
+	sub := newModule(deptree.NewGraph(deptree.WithOptions(m.Options())))
+	sub.Dir = m.Dir
+
+	queue := []int{i}
+	sub.Index(m.Indexes[i])
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		children, ok := m.Packages[current]
+		if !ok {
+			continue
+		}
+		subCurrent := sub.Lookup[m.Indexes[current]]
+		subChildren := make([]int, 0, len(children))
+		for _, child := range children {
+			if _, seen := sub.Lookup[m.Indexes[child]]; !seen {
+				queue = append(queue, child)
+			}
+			subChild := sub.Index(m.Indexes[child])
+			if _, ok := m.Indirect[deptree.Edge{From: current, To: child}]; ok {
+				sub.Indirect[deptree.Edge{From: subCurrent, To: subChild}] = struct{}{}
+			}
+			subChildren = append(subChildren, subChild)
+		}
+		sub.Packages[subCurrent] = subChildren
+	}
+
+	sub.copyModuleState(m)
+	return sub
+}
+
+// copyModuleState copies what is known about each module in sub from the
+// graph m it was taken from.
+func (sub *module) copyModuleState(m *module) {
+	for subIndex, name := range sub.Indexes {
+		original := m.Lookup[name]
+		if _, ok := m.Unknown[original]; ok {
+			sub.Unknown[subIndex] = struct{}{}
+		}
+		if err, ok := m.Errors[original]; ok {
+			sub.Errors[subIndex] = err
+		}
+		if message, ok := m.Deprecated[original]; ok {
+			sub.Deprecated[subIndex] = message
+		}
+		if metadata, ok := m.Metadata[original]; ok {
+			sub.Metadata[subIndex] = metadata
+		}
+		if r, ok := m.Replaced[original]; ok {
+			sub.Replaced[subIndex] = r
+		}
+		if _, ok := m.Excluded[original]; ok {
+			sub.Excluded[subIndex] = struct{}{}
+		}
+		if published, ok := m.PublishTimes[original]; ok {
+			sub.PublishTimes[subIndex] = published
+		}
+		if license, ok := m.licenses[original]; ok {
+			if sub.licenses == nil {
+				sub.licenses = make(map[int]string)
+			}
+			sub.licenses[subIndex] = license
+		}
+		if declared, ok := m.Mismatches[original]; ok {
+			sub.Mismatches[subIndex] = declared
+		}
+		if _, ok := m.SelfRefs[original]; ok {
+			sub.SelfRefs[subIndex] = struct{}{}
+		}
+	}
+
+	// A cycle through a module sub leaves out isn't a cycle in sub, so only
+	// the cycles sub holds whole are kept.
+	for _, cycle := range m.Cycles {
+		subCycle := make([]int, 0, len(cycle))
+		for _, i := range cycle {
+			subIndex, ok := sub.Lookup[m.Indexes[i]]
+			if !ok {
+				break
+			}
+			subCycle = append(subCycle, subIndex)
+		}
+		if len(subCycle) == len(cycle) {
+			sub.Cycles = append(sub.Cycles, subCycle)
+		}
+	}
+}
+
+// groupFileName returns the name of the file, without an extension, the
+// subtree of the module path name is written to. Capitals are escaped as the
+// module cache does, so paths differing only in case don't clash on case
+// insensitive file systems, and other characters that aren't safe in a file
+// name become underscores. As that can still map two paths to the same name,
+// such as example.com/a/b and example.com/a_b, a name already in use gets a
+// numbered suffix.
+func groupFileName(name string, used map[string]bool) string {
+	if escaped, err := gomodule.EscapePath(name); err == nil {
+		name = escaped
+	}
+	base := unsafeFileChars.ReplaceAllString(strings.TrimRight(strings.ToLower(name), "/"), "_")
+	fileName := base
+	for n := 2; used[fileName]; n++ {
+		fileName = base + "-" + strconv.Itoa(n)
+	}
+	used[fileName] = true
+	return fileName
+}
+
+// writeGroups writes the subtree of each of the root's direct requirements to
+// its own file in dir, named after the requirement's module path.
+func writeGroups(dir, format string, m *module, depth int) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if depth > 0 {
+		depth--
+	}
+
+	used := make(map[string]bool)
+	for _, child := range m.Packages[0] {
+		name, _ := deptree.NameAndVersion(m.Indexes[child])
+		fileName := groupFileName(name, used) + formats[format].extension()
+
+		file, err := os.Create(path.Join(dir, fileName))
+		if err != nil {
+			return err
+		}
+		err = writeGraph(file, format, m.subgraph(child), depth)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestGroupOutput(t *testing.T) {
+	dir := t.TempDir()
+	stdout, stderr, code := run(t, "example.com/app", "-groupOutput", dir, "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("output = %q, want nothing printed", stdout)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, filepath.Base(file))
+	}
+	sort.Strings(names)
+	wantNames := []string{"example.com_a.json", "example.com_b.json", "example.com_missing.json"}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Fatalf("files = %q, want %q", names, wantNames)
+	}
+
+	tests := []struct {
+		file string
+		want jsonGraph
+	}{
+		{
+			file: "example.com_a.json",
+			want: jsonGraph{
+				SchemaVersion: jsonSchemaVersion,
+				Indexes:       []string{"example.com/a v1.0.0", "example.com/b v1.0.0", "example.com/c v1.0.0"},
+				Packages:      map[int][]int{0: {1, 2}, 1: {2}, 2: {}},
+				Unknown:       []int{},
+				Metadata: map[string]deptree.ModuleMetadata{
+					"example.com/a v1.0.0": {Go: "1.16"},
+					"example.com/b v1.0.0": {Go: "1.16"},
+					"example.com/c v1.0.0": {Go: "1.16"},
+				},
+			},
+		},
+		{
+			file: "example.com_missing.json",
+			want: jsonGraph{
+				SchemaVersion: jsonSchemaVersion,
+				Indexes:       []string{"example.com/missing v1.0.0"},
+				Packages:      map[int][]int{},
+				Unknown:       []int{0},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.file, func(t *testing.T) {
+			b, err := ioutil.ReadFile(filepath.Join(dir, test.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got jsonGraph
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("%s isn't JSON: %v", test.file, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("%s = %+v, want %+v", test.file, got, test.want)
+			}
+		})
+	}
+}
+
+func TestGroupOutputDefaultsToJSON(t *testing.T) {
+	dir := t.TempDir()
+	_, stderr, code := run(t, "example.com/app", "-groupOutput", dir)
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	b, err := ioutil.ReadFile(filepath.Join(dir, "example.com_missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got jsonGraph
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("example.com_missing.json isn't JSON: %v\n%s", err, b)
+	}
+}
+
+func TestSubgraphKeepsModuleState(t *testing.T) {
+	m := newModule(deptree.NewGraph())
+	for _, name := range []string{
+		"example.com/app",
+		"example.com/x v1.0.0",
+		"example.com/y v1.0.0",
+		"example.com/z v1.0.0",
+	} {
+		m.Index(name)
+	}
+	// x and y require each other, and z requires the root, closing a cycle
+	// the subgraph of x only holds part of.
+	m.Packages[0] = []int{1, 3}
+	m.Packages[1] = []int{2}
+	m.Packages[2] = []int{1}
+	m.Packages[3] = []int{0}
+	m.Cycles = [][]int{{1, 2, 1}, {0, 3, 0}}
+	m.Mismatches[2] = "example.com/other"
+	m.SelfRefs[1] = struct{}{}
+	m.SelfRefs[3] = struct{}{}
+
+	sub := m.subgraph(1)
+	if want := [][]int{{0, 1, 0}}; !reflect.DeepEqual(sub.Cycles, want) {
+		t.Errorf("cycles = %v, want %v", sub.Cycles, want)
+	}
+	if want := map[int]string{1: "example.com/other"}; !reflect.DeepEqual(sub.Mismatches, want) {
+		t.Errorf("mismatches = %v, want %v", sub.Mismatches, want)
+	}
+	if want := map[int]struct{}{0: {}}; !reflect.DeepEqual(sub.SelfRefs, want) {
+		t.Errorf("self references = %v, want %v", sub.SelfRefs, want)
+	}
+}
+
+func TestSubgraphKeepsOptions(t *testing.T) {
+	m := newModule(deptree.NewGraph(deptree.WithModCache("/cache"), deptree.WithNoGoEnv(true)))
+	m.Index("example.com/app")
+	m.Packages[0] = []int{m.Index("example.com/a v1.0.0")}
+	if got := m.subgraph(1).Options(); got.ModCache != "/cache" || !got.NoGoEnv {
+		t.Errorf("options = %+v, want those of the graph it was taken from", got)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// anomaly is a category of problem found in the graph and the modules it
+// affects.
+type anomaly struct {
+	category string
+	entries  []string
+}
+
+// anomalies returns every category of problem found while listing the graph
+// that has at least one entry.
+func (m *module) anomalies() []anomaly {
+	// A replaced module that can't be found or read is down to a replace
+	// whose target doesn't exist or can't be read, so is reported as that
+	// rather than as an unknown module or parse error.
+	brokenReplaces := make([]string, 0)
+	unknown := make([]string, 0)
+	for i := range m.Unknown {
+		if r, ok := m.Replaced[i]; ok {
+			brokenReplaces = append(brokenReplaces, m.Indexes[i]+": replacement "+strings.TrimSpace(r.New+" "+r.NewVersion)+" not found")
+			continue
+		}
+		unknown = append(unknown, m.Indexes[i])
+	}
+	parseErrors := make([]string, 0)
+	for i, err := range m.Errors {
+		if r, ok := m.Replaced[i]; ok {
+			brokenReplaces = append(brokenReplaces, m.Indexes[i]+": replacement "+strings.TrimSpace(r.New+" "+r.NewVersion)+": "+err.Error())
+			continue
+		}
+		parseErrors = append(parseErrors, m.Indexes[i]+": "+err.Error())
+	}
+	cycles := make([]string, 0)
+	for _, names := range m.cycleNames() {
+		cycles = append(cycles, strings.Join(names, " -> "))
+	}
+	mismatches := make([]string, 0)
+	for i, name := range m.Mismatches {
+		mismatches = append(mismatches, m.Indexes[i]+" declares itself as "+name)
+	}
+	selfRefs := make([]string, 0)
+	for i := range m.SelfRefs {
+		selfRefs = append(selfRefs, m.Indexes[i])
+	}
+
+	all := []anomaly{
+		{category: "Unknown modules", entries: unknown},
+		{category: "Cycles", entries: cycles},
+		{category: "Parse errors", entries: parseErrors},
+		{category: "Broken replaces", entries: brokenReplaces},
+		{category: "Path mismatches", entries: mismatches},
+		{category: "Self-references", entries: selfRefs},
+	}
+	found := make([]anomaly, 0)
+	for _, a := range all {
+		if len(a.entries) > 0 {
+			sort.Strings(a.entries)
+			found = append(found, a)
+		}
+	}
+	return found
+}
+
+// cycleNames returns each cycle found while listing the graph as the names
+// of the modules in it, in require order, starting and ending with the same
+// module.
+func (m *module) cycleNames() [][]string {
+	cycles := make([][]string, 0, len(m.Cycles))
+	for _, cycle := range m.Cycles {
+		names := make([]string, 0, len(cycle))
+		for _, i := range cycle {
+			names = append(names, m.Indexes[i])
+		}
+		cycles = append(cycles, names)
+	}
+	return cycles
+}
+
+// printCycles writes every cycle found while listing the graph.
+func printCycles(w io.Writer, m *module) {
+	if len(m.Cycles) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Cycles:")
+	for _, names := range m.cycleNames() {
+		fmt.Fprintln(w, "  "+strings.Join(names, " -> "))
+	}
+}
+
+// moduleError is a module whose go.mod was found but couldn't be read.
+type moduleError struct {
+	Module string `json:"module"`
+	Error  string `json:"error"`
+}
+
+// moduleErrors returns every module whose go.mod couldn't be read, in the
+// order they were found. The walk carries on past them, but their
+// requirements are missing from the graph.
+func (m *module) moduleErrors() []moduleError {
+	errs := make([]moduleError, 0, len(m.Errors))
+	for i, name := range m.Indexes {
+		if err, ok := m.Errors[i]; ok {
+			errs = append(errs, moduleError{Module: name, Error: err.Error()})
+		}
+	}
+	return errs
+}
+
+// printModuleErrors writes every module whose go.mod couldn't be read.
+func printModuleErrors(w io.Writer, prefix string, errs []moduleError) {
+	for _, e := range errs {
+		fmt.Fprintln(w, prefix+"Unable to read the go.mod of "+e.Module+", its dependencies are missing: "+e.Error)
+	}
+}
+
+// checkTree runs every check enabled on the command line against the graph,
+// writing a report of each failure to w with every heading prefixed by
+// prefix. It returns false if any check failed. With -redact the checks still
+// see the real module paths, so policy rules naming private modules apply,
+// but the report is redacted as the output is.
+func checkTree(w io.Writer, prefix string, m *module) bool {
+	if *redact == "" {
+		return runChecks(w, prefix, m)
+	}
+	var report strings.Builder
+	passed := runChecks(&report, prefix, m)
+	r := redactor{prefixes: strings.Split(*redact, ","), versions: *redactVersions}
+	if _, err := io.WriteString(w, r.message(m, report.String())); err != nil {
+		return false
+	}
+	return passed
+}
+
+// runChecks runs the checks of checkTree, writing the reports unredacted.
+func runChecks(w io.Writer, prefix string, m *module) bool {
+	passed := true
+	if errs := m.moduleErrors(); len(errs) > 0 {
+		printModuleErrors(w, prefix, errs)
+		if *strict {
+			passed = false
+		}
+	}
+	if *requireCleanTree {
+		if anomalies := m.anomalies(); len(anomalies) > 0 {
+			printAnomalies(w, prefix, anomalies)
+			passed = false
+		}
+	}
+	if m.sums != nil && m.sums.failed() {
+		printSumVerification(w, prefix, m.sums)
+		passed = false
+	}
+	if *failOnCycle && len(m.Cycles) > 0 {
+		printClosedCycles(w, prefix, m)
+		passed = false
+	}
+	if *failOnDuplicateMajor {
+		duplicates := make([]versionConflict, 0)
+		for _, conflict := range m.conflicts() {
+			if len(conflict.Majors) > 0 {
+				duplicates = append(duplicates, conflict)
+			}
+		}
+		if len(duplicates) > 0 {
+			printConflicts(w, prefix+"Modules found at more than one major version:", duplicates)
+			passed = false
+		}
+	}
+	if *maxSamePathVersions > 0 {
+		if fragmented := m.fragmentedModules(*maxSamePathVersions); len(fragmented) > 0 {
+			printFragmentedModules(w, prefix, fragmented)
+			passed = false
+		}
+	}
+	if *allowedLicenses != "" {
+		if disallowed := m.disallowedLicenses(strings.Split(*allowedLicenses, ",")); len(disallowed) > 0 {
+			printDisallowedLicenses(w, prefix, disallowed)
+			passed = false
+		}
+	}
+	if treePolicy != nil {
+		if violations := m.policyViolations(*treePolicy); len(violations) > 0 {
+			printPolicyViolations(w, prefix, violations)
+			passed = false
+		}
+	}
+	if *vuln {
+		if severe := m.severeVulnerabilities(*vulnSeverity); len(severe) > 0 {
+			printVulnerabilities(w, prefix+"Vulnerabilities rated "+*vulnSeverity+" or above:", severe)
+			passed = false
+		}
+	}
+	return passed
+}
+
+// printAnomalies writes a report of the given anomalies.
+func printAnomalies(w io.Writer, prefix string, anomalies []anomaly) {
+	fmt.Fprintln(w, prefix+"Dependency tree is not clean:")
+	for _, a := range anomalies {
+		fmt.Fprintln(w, "  "+a.category+":")
+		for _, entry := range a.entries {
+			fmt.Fprintln(w, "    "+entry)
+		}
+	}
+}
+
+// printClosedCycles writes a report of every cycle along with the require
+// closing it, which is the one to break to remove the cycle from this walk.
+func printClosedCycles(w io.Writer, prefix string, m *module) {
+	fmt.Fprintln(w, prefix+"Dependency tree has cycles:")
+	for _, names := range m.cycleNames() {
+		fmt.Fprintln(w, "  "+strings.Join(names, " -> "))
+		fmt.Fprintln(w, "    closed by "+names[len(names)-2]+" -> "+names[len(names)-1])
+	}
+}
+
+// printFragmentedModules writes a report of the module paths required at too
+// many versions.
+func printFragmentedModules(w io.Writer, prefix string, fragmented []versionSpread) {
+	fmt.Fprintln(w, prefix+"Modules required at more than "+strconv.Itoa(*maxSamePathVersions)+" versions:")
+	for _, spread := range fragmented {
+		fmt.Fprintln(w, "  "+spread.Module+": "+strings.Join(spread.Versions, ", "))
+	}
+}
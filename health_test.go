@@ -0,0 +1,176 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRequireCleanTree(t *testing.T) {
+	tests := []struct {
+		name       string
+		modPath    string
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			name:     "clean",
+			modPath:  "example.com/clean",
+			wantCode: 0,
+		},
+		{
+			name:     "unknown module",
+			modPath:  "example.com/app",
+			wantCode: 1,
+			wantStderr: `Dependency tree is not clean:
+  Unknown modules:
+    example.com/missing v1.0.0
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout, stderr, code := run(t, test.modPath, "-requireCleanTree")
+			if code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if stderr != test.wantStderr {
+				t.Errorf("stderr =\n%s\nwant\n%s", stderr, test.wantStderr)
+			}
+			if !strings.HasPrefix(stdout, test.modPath+":\n") {
+				t.Errorf("output =\n%s\nwant the tree of %s", stdout, test.modPath)
+			}
+		})
+	}
+}
+
+func TestMaxSamePathVersions(t *testing.T) {
+	tests := []struct {
+		name       string
+		limit      string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "no limit", limit: "0"},
+		{name: "within the limit", limit: "2"},
+		{
+			name:     "over the limit",
+			limit:    "1",
+			wantCode: 1,
+			wantStderr: `Modules required at more than 1 versions:
+  example.com/b: v1.0.0, v1.1.0
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, stderr, code := run(t, "example.com/app", "-maxSamePathVersions", test.limit)
+			if code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if stderr != test.wantStderr {
+				t.Errorf("stderr =\n%s\nwant\n%s", stderr, test.wantStderr)
+			}
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	gopath := fixtureGopath(t)
+	wantStderr := "Unable to read the go.mod of example.com/broken v1.0.0, its dependencies are missing: read " +
+		filepath.Join(gopath, "pkg", "mod", "example.com", "broken@v1.0.0", "go.mod") + ": is a directory\n"
+	tests := []struct {
+		name     string
+		args     []string
+		wantCode int
+	}{
+		{name: "reported", wantCode: 0},
+		{name: "strict", args: []string{"-strict"}, wantCode: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout, stderr, code := run(t, "example.com/strict", test.args...)
+			if code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if stderr != wantStderr {
+				t.Errorf("stderr =\n%s\nwant\n%s", stderr, wantStderr)
+			}
+			// The rest of the tree is still walked.
+			if want := "example.com/strict:\n  example.com/broken v1.0.0\n  example.com/c v1.0.0:\n"; stdout != want {
+				t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+			}
+		})
+	}
+}
+
+func TestFailOnCycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		modPath    string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "no cycles", modPath: "example.com/app"},
+		{
+			name:     "cycle",
+			modPath:  "example.com/cyclic",
+			wantCode: 1,
+			wantStderr: `Dependency tree has cycles:
+  example.com/ping v1.0.0 -> example.com/pong v1.0.0 -> example.com/ping v1.0.0
+    closed by example.com/pong v1.0.0 -> example.com/ping v1.0.0
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, stderr, code := run(t, test.modPath, "-failOnCycle")
+			if code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if stderr != test.wantStderr {
+				t.Errorf("stderr =\n%s\nwant\n%s", stderr, test.wantStderr)
+			}
+		})
+	}
+
+	// Cycles are listed either way, the flag only makes them fail the run.
+	if _, _, code := run(t, "example.com/cyclic"); code != 0 {
+		t.Errorf("exit code without -failOnCycle = %d, want 0", code)
+	}
+}
+
+func TestFailOnDuplicateMajor(t *testing.T) {
+	tests := []struct {
+		name       string
+		modPath    string
+		wantCode   int
+		wantStderr string
+	}{
+		// example.com/b is found at two versions, but of one major version.
+		{name: "one major version", modPath: "example.com/app"},
+		{
+			name:     "two major versions",
+			modPath:  "example.com/majors",
+			wantCode: 1,
+			wantStderr: `Modules found at more than one major version:
+  example.com/c (2 major versions):
+    example.com/c v1.0.0
+      v1.0.0: example.com/majors -> example.com/b v1.1.0 -> example.com/c v1.0.0
+    example.com/c/v2 v2.0.0
+      v2.0.0: example.com/majors -> example.com/c/v2 v2.0.0
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, stderr, code := run(t, test.modPath, "-failOnDuplicateMajor")
+			if code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if stderr != test.wantStderr {
+				t.Errorf("stderr =\n%s\nwant\n%s", stderr, test.wantStderr)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// suspectRequire is a require of the root module marked // indirect that
+// looks like it may be a direct dependency.
+type suspectRequire struct {
+	Module     string `json:"module"`
+	RequiredBy int    `json:"requiredBy"`
+}
+
+// requiredBy counts, for every module path, how many distinct modules in the
+// graph require it at any version.
+func (m *module) requiredBy() map[string]int {
+	parents := make(map[string]map[int]struct{})
+	for i := range m.Indexes {
+		for _, child := range m.Packages[i] {
+			modPath, _ := deptree.SplitModuleName(m.Indexes[child])
+			if parents[modPath] == nil {
+				parents[modPath] = make(map[int]struct{})
+			}
+			parents[modPath][i] = struct{}{}
+		}
+	}
+	counts := make(map[string]int)
+	for modPath, from := range parents {
+		counts[modPath] = len(from)
+	}
+	return counts
+}
+
+// suspectIndirect returns the root's requires that are marked // indirect but
+// are also the most commonly required module paths in the graph.
+//
+// This is only a heuristic: without looking at the root's imports there's no
+// way to know whether a module is really imported directly. A module that
+// everything depends on is, however, a module the root is likely to import
+// itself, so these are worth checking against go mod tidy.
+func (m *module) suspectIndirect() []suspectRequire {
+	counts := m.requiredBy()
+	most := 0
+	for _, count := range counts {
+		if count > most {
+			most = count
+		}
+	}
+
+	suspects := make([]suspectRequire, 0)
+	for _, child := range m.Packages[0] {
+		if _, ok := m.Indirect[deptree.Edge{From: 0, To: child}]; !ok {
+			continue
+		}
+		modPath, _ := deptree.SplitModuleName(m.Indexes[child])
+		if counts[modPath] == most {
+			suspects = append(suspects, suspectRequire{
+				Module:     m.Indexes[child],
+				RequiredBy: counts[modPath],
+			})
+		}
+	}
+	return suspects
+}
+
+// printSuspectIndirect writes the root's suspect indirect requires.
+func printSuspectIndirect(w io.Writer, m *module) {
+	fmt.Fprintln(w, "Suspect indirect requires (heuristic, check with go mod tidy):")
+	for _, suspect := range m.suspectIndirect() {
+		fmt.Fprintf(w, "  %s: required by %d modules\n", suspect.Module, suspect.RequiredBy)
+	}
+}
+
+// directRequires splits the modules the root requires into those it requires
+// directly and those its go.mod marks // indirect.
+func (m *module) directRequires() ([]string, []string) {
+	direct, indirect := make([]string, 0), make([]string, 0)
+	for _, child := range m.Packages[0] {
+		if _, ok := m.Indirect[deptree.Edge{From: 0, To: child}]; ok {
+			indirect = append(indirect, m.Indexes[child])
+		} else {
+			direct = append(direct, m.Indexes[child])
+		}
+	}
+	return direct, indirect
+}
+
+// printDirectRequires writes the root and the modules it requires, marking
+// those its go.mod marks indirect as go.mod does.
+func printDirectRequires(w io.Writer, m *module) {
+	fmt.Fprintln(w, m.Indexes[0]+":")
+	for _, child := range m.Packages[0] {
+		name := m.treeLabel(child)
+		if _, ok := m.Indirect[deptree.Edge{From: 0, To: child}]; ok {
+			name += " // indirect"
+		}
+		fmt.Fprintln(w, *indentText+name)
+	}
+}
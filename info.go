@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// printPublishTimes writes the publish time of every module that has one.
+func printPublishTimes(w io.Writer, m *module) {
+	if !*withInfo || len(m.PublishTimes) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Publish times:")
+	for i, name := range m.Indexes {
+		if published, ok := m.PublishTimes[i]; ok {
+			fmt.Fprintln(w, "  "+name+": "+published.Format(time.RFC3339))
+		}
+	}
+}
+
+// recentlyPublished returns the indexes of the modules published after the
+// given time.
+func (m *module) recentlyPublished(after time.Time) []int {
+	recent := make([]int, 0)
+	for i := range m.Indexes {
+		if published, ok := m.PublishTimes[i]; ok && published.After(after) {
+			recent = append(recent, i)
+		}
+	}
+	return recent
+}
+
+// printRecentlyPublished writes every module published after the given time.
+func printRecentlyPublished(w io.Writer, m *module, after time.Time) {
+	fmt.Fprintln(w, "Recently published:")
+	for _, i := range m.recentlyPublished(after) {
+		fmt.Fprintln(w, "  "+m.Indexes[i]+": "+m.PublishTimes[i].Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// jsonSchemaVersion is the version of the json and ndjson formats, bumped
+// whenever a change could break their consumers. Adding fields doesn't.
+const jsonSchemaVersion = 1
+
+// jsonGraph is the JSON representation of a module graph. Modules are
+// referred to by their position in Indexes, the first being the root.
+type jsonGraph struct {
+	SchemaVersion int `json:"schemaVersion,omitempty"`
+
+	Indexes      []string                            `json:"indexes,omitempty"`
+	Packages     map[int][]int                       `json:"packages"`
+	Unknown      []int                               `json:"unknown"`
+	Errors       []moduleError                       `json:"errors,omitempty"`
+	Direct       []string                            `json:"direct,omitempty"`
+	Indirect     []string                            `json:"indirect,omitempty"`
+	Deprecated   map[string]string                   `json:"deprecated,omitempty"`
+	Metadata     map[string]deptree.ModuleMetadata   `json:"metadata,omitempty"`
+	Replaced     map[string]deptree.ReplaceDirective `json:"replaced,omitempty"`
+	Excluded     []string                            `json:"excluded,omitempty"`
+	Depths       map[string]int                      `json:"depths,omitempty"`
+	Cycles       [][]string                          `json:"cycles,omitempty"`
+	Conflicts    []versionConflict                   `json:"conflicts,omitempty"`
+	PublishTimes map[string]time.Time                `json:"publishTimes,omitempty"`
+
+	RecentlyPublished []jsonPublished            `json:"recentlyPublished,omitempty"`
+	DowngradeRisk     []versionSpread            `json:"downgradeRisk,omitempty"`
+	Resolved          []resolvedVersion          `json:"resolved,omitempty"`
+	GoSum             *sumVerification           `json:"goSum,omitempty"`
+	Outdated          []outdatedModule           `json:"outdated,omitempty"`
+	Vulnerabilities   []vulnerability            `json:"vulnerabilities,omitempty"`
+	Licenses          map[string]string          `json:"licenses,omitempty"`
+	LicenseSummary    map[string][]string        `json:"licenseSummary,omitempty"`
+	SuspectIndirect   []suspectRequire           `json:"suspectIndirect,omitempty"`
+	InvalidVersions   []string                   `json:"invalidVersions,omitempty"`
+	CoveringSet       []string                   `json:"coveringSet,omitempty"`
+	CoalescedVersions []deptree.CoalescedRequire `json:"coalescedVersions,omitempty"`
+	PackageImports    []packageImport            `json:"packageImports,omitempty"`
+	SkippedFiles      []skippedFile              `json:"skippedFiles,omitempty"`
+	Stats             *treeStats                 `json:"stats,omitempty"`
+	GraphStats        *graphStats                `json:"graphStats,omitempty"`
+	UnusedReplaces    []deptree.ReplaceDirective `json:"unusedReplaces,omitempty"`
+}
+
+type jsonPublished struct {
+	Module    string    `json:"module"`
+	Published time.Time `json:"published"`
+}
+
+// writeJSON writes the graph as an indented JSON document.
+func writeJSON(w io.Writer, m *module) error {
+	return writeJSONValue(w, newJSONGraph(m), &jsonGraph{})
+}
+
+// newJSONGraph returns the JSON representation of the graph.
+func newJSONGraph(m *module) jsonGraph {
+	graph := jsonGraph{
+		SchemaVersion: jsonSchemaVersion,
+		Indexes:       m.Indexes,
+		Packages:      m.Packages,
+		Unknown:       make([]int, 0, len(m.Unknown)),
+	}
+	for i := range m.Indexes {
+		if _, ok := m.Unknown[i]; ok {
+			graph.Unknown = append(graph.Unknown, i)
+		}
+	}
+	if len(m.Deprecated) > 0 {
+		graph.Deprecated = make(map[string]string)
+		for i, message := range m.Deprecated {
+			graph.Deprecated[m.Indexes[i]] = message
+		}
+	}
+	graph.Errors = m.moduleErrors()
+	if *directOnly {
+		graph.Direct, graph.Indirect = m.directRequires()
+	}
+	if len(m.Metadata) > 0 {
+		graph.Metadata = make(map[string]deptree.ModuleMetadata)
+		for i, metadata := range m.Metadata {
+			graph.Metadata[m.Indexes[i]] = metadata
+		}
+	}
+	if len(m.Replaced) > 0 {
+		graph.Replaced = make(map[string]deptree.ReplaceDirective)
+		for i, r := range m.Replaced {
+			graph.Replaced[m.Indexes[i]] = r
+		}
+	}
+	for i, name := range m.Indexes {
+		if _, ok := m.Excluded[i]; ok {
+			graph.Excluded = append(graph.Excluded, name)
+		}
+	}
+	if *showDepths {
+		graph.Depths = make(map[string]int)
+		for i, depth := range m.Depths() {
+			graph.Depths[m.Indexes[i]] = depth
+		}
+	}
+	graph.Cycles = m.cycleNames()
+	graph.Conflicts = m.conflicts()
+	if *publishedAfter != "" {
+		graph.RecentlyPublished = make([]jsonPublished, 0)
+		for _, i := range m.recentlyPublished(publishedAfterTime) {
+			graph.RecentlyPublished = append(graph.RecentlyPublished, jsonPublished{
+				Module:    m.Indexes[i],
+				Published: m.PublishTimes[i],
+			})
+		}
+	}
+	if *downgradeRisk {
+		graph.DowngradeRisk = m.downgradeRisks()
+	}
+	if *resolved {
+		graph.Resolved = m.resolvedVersions()
+	}
+	graph.GoSum = m.sums
+	if m.outdated != nil {
+		graph.Outdated = m.outdated
+	}
+	if *vuln {
+		graph.Vulnerabilities = m.vulns
+	}
+	if m.licenses != nil {
+		graph.Licenses = make(map[string]string)
+		for i, license := range m.licenses {
+			graph.Licenses[m.Indexes[i]] = license
+		}
+		graph.LicenseSummary = m.licenseGroups()
+	}
+	if *suspectIndirect {
+		graph.SuspectIndirect = m.suspectIndirect()
+	}
+	if *strictSemver {
+		graph.InvalidVersions = m.invalidVersions()
+	}
+	if *coveringSet {
+		graph.CoveringSet = make([]string, 0)
+		for _, i := range m.coveringSet() {
+			graph.CoveringSet = append(graph.CoveringSet, m.Indexes[i])
+		}
+	}
+	if len(m.Coalesced) > 0 {
+		graph.CoalescedVersions = m.Coalesced
+	}
+	if *packages {
+		graph.PackageImports = m.imports
+		graph.SkippedFiles = m.skippedFiles
+	}
+	if *showStats {
+		summary := m.stats()
+		graph.Stats = &summary
+	}
+	if *showGraphStats {
+		summary := m.graphStats()
+		graph.GraphStats = &summary
+	}
+	if *unusedReplaces {
+		graph.UnusedReplaces = m.unusedReplaces()
+	}
+	if *withInfo && len(m.PublishTimes) > 0 {
+		graph.PublishTimes = make(map[string]time.Time)
+		for i, published := range m.PublishTimes {
+			graph.PublishTimes[m.Indexes[i]] = published
+		}
+	}
+
+	return graph
+}
+
+// writeJSONValue writes v as an indented JSON document. With -validateOutput
+// the document is first checked by round tripping it through empty, which
+// must be a pointer to a new value of the same type as v.
+func writeJSONValue(w io.Writer, v, empty interface{}) error {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *validateOutput {
+		if err := validateJSON(b, empty); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
+
+// validateJSON checks that the JSON in b survives a round trip through v,
+// catching output that is malformed or has fields which don't serialize
+// faithfully.
+func validateJSON(b []byte, v interface{}) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("output failed validation: %v", err)
+	}
+	again, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("output failed validation: %v", err)
+	}
+	if !bytes.Equal(b, again) {
+		return errors.New("output failed validation: output changed after a round trip")
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestJSON(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var got jsonGraph
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, stdout)
+	}
+	want := jsonGraph{
+		SchemaVersion: jsonSchemaVersion,
+		Indexes: []string{
+			"example.com/app",
+			"example.com/a v1.0.0",
+			"example.com/b v1.0.0",
+			"example.com/c v1.0.0",
+			"example.com/b v1.1.0",
+			"example.com/missing v1.0.0",
+		},
+		Packages: map[int][]int{0: {1, 4, 5}, 1: {2, 3}, 2: {3}, 3: {}, 4: {3}},
+		Unknown:  []int{5},
+		Metadata: map[string]deptree.ModuleMetadata{
+			"example.com/app":      {Go: "1.16"},
+			"example.com/a v1.0.0": {Go: "1.16"},
+			"example.com/b v1.0.0": {Go: "1.16"},
+			"example.com/c v1.0.0": {Go: "1.16"},
+			"example.com/b v1.1.0": {Go: "1.16"},
+		},
+		Conflicts: []versionConflict{{
+			Module: "example.com/b",
+			Base:   "example.com/b",
+			Versions: []conflictingVersion{
+				{
+					Version:    "v1.0.0",
+					RequiredBy: []string{"example.com/a v1.0.0"},
+					Path:       []string{"example.com/app", "example.com/a v1.0.0", "example.com/b v1.0.0"},
+				},
+				{
+					Version:    "v1.1.0",
+					RequiredBy: []string{"example.com/app"},
+					Path:       []string{"example.com/app", "example.com/b v1.1.0"},
+				},
+			},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("output = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+)
+
+// unknownLicense is the license of a module whose license couldn't be found
+// or recognised.
+const unknownLicense = "unknown"
+
+// licensePatterns recognises the common open source licenses by phrases from
+// their text, each mapped to its SPDX identifier. They're tried in order, so
+// the more specific come first.
+var licensePatterns = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"EPL-2.0", []string{"Eclipse Public License", "2.0"}},
+	{"EPL-1.0", []string{"Eclipse Public License"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"CC0 1.0 Universal"}},
+}
+
+// classifyLicense returns the SPDX identifier of the license text, or
+// unknownLicense if it isn't one of licensePatterns.
+func classifyLicense(text string) string {
+	// Line wrapping differs between copies of the same license.
+	text = strings.Join(strings.Fields(text), " ")
+	for _, pattern := range licensePatterns {
+		matched := true
+		for _, phrase := range pattern.phrases {
+			if !strings.Contains(text, phrase) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return pattern.id
+		}
+	}
+	return unknownLicense
+}
+
+// readLicense classifies the license of the module in dir, read from the
+// first of its LICENSE, LICENCE or COPYING files that's recognised.
+func readLicense(dir string) string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return unknownLicense
+	}
+	for _, file := range files {
+		name := strings.ToUpper(file.Name())
+		if file.IsDir() || !(strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "LICENCE") || strings.HasPrefix(name, "COPYING")) {
+			continue
+		}
+		text, err := ioutil.ReadFile(path.Join(dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		if id := classifyLicense(string(text)); id != unknownLicense {
+			return id
+		}
+	}
+	return unknownLicense
+}
+
+// readLicenses classifies the license of every module in the tree but the
+// root whose source is on disk, the rest being unknown.
+func (m *module) readLicenses() {
+	m.licenses = make(map[int]string)
+	for i := range m.Indexes[1:] {
+		i++
+		if dir, ok := m.ModuleDir(i); ok {
+			m.licenses[i] = readLicense(dir)
+		} else {
+			m.licenses[i] = unknownLicense
+		}
+	}
+}
+
+// licenseGroups returns the modules under each license found, keyed by SPDX
+// identifier, each list sorted.
+func (m *module) licenseGroups() map[string][]string {
+	groups := make(map[string][]string)
+	for i, id := range m.licenses {
+		groups[id] = append(groups[id], m.Indexes[i])
+	}
+	for _, names := range groups {
+		sort.Strings(names)
+	}
+	return groups
+}
+
+// disallowedLicenses returns the modules whose license isn't one of allowed,
+// including those whose license is unknown, each followed by its license.
+func (m *module) disallowedLicenses(allowed []string) []string {
+	ok := make(map[string]bool)
+	for _, id := range allowed {
+		ok[strings.TrimSpace(id)] = true
+	}
+	disallowed := make([]string, 0)
+	for i, id := range m.licenses {
+		if !ok[id] {
+			disallowed = append(disallowed, m.Indexes[i]+" ("+id+")")
+		}
+	}
+	sort.Strings(disallowed)
+	return disallowed
+}
+
+// printLicenses writes the modules in the tree grouped by license, the most
+// common license first.
+func printLicenses(w io.Writer, m *module) {
+	groups := m.licenseGroups()
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool {
+		if len(groups[ids[a]]) != len(groups[ids[b]]) {
+			return len(groups[ids[a]]) > len(groups[ids[b]])
+		}
+		return ids[a] < ids[b]
+	})
+	fmt.Fprintln(w, "Licenses:")
+	for _, id := range ids {
+		fmt.Fprintf(w, "  %s (%d):\n", id, len(groups[id]))
+		for _, name := range groups[id] {
+			fmt.Fprintln(w, "    "+name)
+		}
+	}
+}
+
+// printDisallowedLicenses writes a report of the modules whose license isn't
+// allowed.
+func printDisallowedLicenses(w io.Writer, prefix string, disallowed []string) {
+	fmt.Fprintln(w, prefix+"Modules with licenses not in -allowedLicenses:")
+	for _, name := range disallowed {
+		fmt.Fprintln(w, "  "+name)
+	}
+}
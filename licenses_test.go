@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestClassifyLicense(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "mit", text: "Permission is hereby granted,\n  free of charge, to any person", want: "MIT"},
+		{name: "apache", text: "Apache License\nVersion 2.0, January 2004", want: "Apache-2.0"},
+		{name: "bsd 3 clause", text: "Redistribution and use in source and binary forms ... Neither the name of", want: "BSD-3-Clause"},
+		{name: "bsd 2 clause", text: "Redistribution and use in source and binary forms", want: "BSD-2-Clause"},
+		{name: "lgpl before gpl", text: "GNU LESSER GENERAL PUBLIC LICENSE Version 3", want: "LGPL-3.0"},
+		{name: "gpl 2", text: "GNU GENERAL PUBLIC LICENSE Version 2", want: "GPL-2.0"},
+		{name: "unrecognised", text: "All rights reserved.", want: unknownLicense},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := classifyLicense(test.text); got != test.want {
+				t.Errorf("classifyLicense() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLicenses(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-licenses")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `example.com/app:
+  example.com/a v1.0.0 [Apache-2.0]:
+    example.com/b v1.0.0 [unknown]:
+      example.com/c v1.0.0 [MIT]:
+    example.com/c v1.0.0 [MIT]:
+  example.com/b v1.1.0 [unknown]:
+    example.com/c v1.0.0 [MIT]:
+  example.com/missing v1.0.0 [unknown]
+Licenses:
+  unknown (3):
+    example.com/b v1.0.0
+    example.com/b v1.1.0
+    example.com/missing v1.0.0
+  Apache-2.0 (1):
+    example.com/a v1.0.0
+  MIT (1):
+    example.com/c v1.0.0
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+
+	stdout, stderr, code = run(t, "example.com/app", "-licenses", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var graph jsonGraph
+	if err := json.Unmarshal([]byte(stdout), &graph); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, stdout)
+	}
+	wantLicenses := map[string]string{
+		"example.com/a v1.0.0":       "Apache-2.0",
+		"example.com/b v1.0.0":       unknownLicense,
+		"example.com/b v1.1.0":       unknownLicense,
+		"example.com/c v1.0.0":       "MIT",
+		"example.com/missing v1.0.0": unknownLicense,
+	}
+	if !reflect.DeepEqual(graph.Licenses, wantLicenses) {
+		t.Errorf("licenses = %q, want %q", graph.Licenses, wantLicenses)
+	}
+}
+
+func TestAllowedLicenses(t *testing.T) {
+	tests := []struct {
+		name       string
+		modPath    string
+		allowed    string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "allowed", modPath: "example.com/clean", allowed: "MIT"},
+		{
+			name:     "not allowed",
+			modPath:  "example.com/clean",
+			allowed:  "Apache-2.0,BSD-3-Clause",
+			wantCode: 1,
+			wantStderr: `Modules with licenses not in -allowedLicenses:
+  example.com/c v1.0.0 (MIT)
+`,
+		},
+		{
+			name:     "unknown is never allowed",
+			modPath:  "example.com/app",
+			allowed:  "MIT,Apache-2.0",
+			wantCode: 1,
+			wantStderr: `Modules with licenses not in -allowedLicenses:
+  example.com/b v1.0.0 (unknown)
+  example.com/b v1.1.0 (unknown)
+  example.com/missing v1.0.0 (unknown)
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, stderr, code := run(t, test.modPath, "-allowedLicenses", test.allowed)
+			if code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if stderr != test.wantStderr {
+				t.Errorf("stderr =\n%s\nwant\n%s", stderr, test.wantStderr)
+			}
+		})
+	}
+}
+
+func TestLicensesInFormats(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{format: "pajek", want: `4 "example.com/c v1.0.0 [MIT]"`},
+		{format: "cypher", want: `  {id: 3, path: "example.com/c", version: "v1.0.0", license: "MIT"},`},
+		{format: "opml", want: `<outline text="example.com/c" version="v1.0.0" license="MIT"></outline>`},
+		{format: "toposort", want: "example.com/c v1.0.0 [MIT]"},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			stdout, stderr, code := run(t, "example.com/app", "-licenses", "-format", test.format)
+			if code != 0 {
+				t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+			}
+			if !strings.Contains(stdout, test.want+"\n") {
+				t.Errorf("output doesn't contain %s:\n%s", test.want, stdout)
+			}
+		})
+	}
+}
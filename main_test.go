@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// runMainEnv is set in the environment of the test binary when run starts it
+// to run the tool rather than the tests.
+const runMainEnv = "GO_TREE_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fixtureGopath returns the absolute path of the GOPATH under testdata that
+// every module the tests walk is resolved from.
+func fixtureGopath(t *testing.T) string {
+	t.Helper()
+	gopath, err := filepath.Abs(filepath.Join("testdata", "gopath"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gopath
+}
+
+// run runs the tool with args in the directory of the fixture module modPath,
+// returning what it wrote to stdout and stderr and its exit code.
+func run(t *testing.T, modPath string, args ...string) (string, string, int) {
+	t.Helper()
+	return runEnv(t, nil, modPath, args...)
+}
+
+// runEnv is run with env added to the tool's environment.
+func runEnv(t *testing.T, env []string, modPath string, args ...string) (string, string, int) {
+	t.Helper()
+	gopath := fixtureGopath(t)
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Dir = filepath.Join(gopath, "src", filepath.FromSlash(modPath))
+	cmd.Env = append(os.Environ(), runMainEnv+"=1", "GOPATH="+gopath, "GOMODCACHE="+filepath.Join(gopath, "pkg", "mod"))
+	// Give each run a cache directory of its own, so go.mod files parsed
+	// from an older copy of the fixtures are never used.
+	cmd.Env = append(cmd.Env, "XDG_CACHE_HOME="+t.TempDir())
+	// Modules missing from the fixtures are missing, whatever the network.
+	cmd.Env = append(cmd.Env, "GOPROXY=off")
+	cmd.Env = append(cmd.Env, env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	code := 0
+	if err := cmd.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatalf("running %v: %v", args, err)
+		}
+		code = exitErr.ExitCode()
+	}
+	return stdout.String(), stderr.String(), code
+}
+
+func TestText(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `example.com/app:
+  example.com/a v1.0.0:
+    example.com/b v1.0.0:
+      example.com/c v1.0.0:
+    example.com/c v1.0.0:
+  example.com/b v1.1.0:
+    example.com/c v1.0.0:
+  example.com/missing v1.0.0
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	stdout, _, _ := run(t, "example.com/app", "-maxDepth", "1")
+	want := `example.com/app:
+  example.com/a v1.0.0
+  example.com/b v1.1.0
+  example.com/missing v1.0.0
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writeMermaid writes the graph as a Mermaid flowchart, which renders in
+// Markdown on most code hosts. Each module is labelled path@version, the root
+// is drawn bold and unknown modules are drawn dashed. With -weights each edge
+// is labelled with the number of modules reachable through it.
+func writeMermaid(w io.Writer, m *module) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintln(out, "graph TD")
+	fmt.Fprintln(out, "  classDef root stroke-width:3px,font-weight:bold")
+	fmt.Fprintln(out, "  classDef unknown stroke-dasharray:5 5")
+	for i := range m.Indexes {
+		label := strings.Replace(m.nodeLabel(i), " ", "@", 1)
+		// Mermaid labels are quoted, and quotes in them are written as an
+		// entity instead.
+		fmt.Fprintf(out, "  m%d[\"%s\"]\n", i, strings.Replace(label, "\"", "#quot;", -1))
+	}
+	var sizes []int
+	if *weights {
+		sizes = m.subtreeSizes()
+	}
+	for i := range m.Indexes {
+		for _, child := range m.Packages[i] {
+			if *weights {
+				fmt.Fprintf(out, "  m%d -->|%d| m%d\n", i, sizes[child], child)
+			} else {
+				fmt.Fprintf(out, "  m%d --> m%d\n", i, child)
+			}
+		}
+	}
+	fmt.Fprintln(out, "  class m0 root")
+	for i := range m.Indexes {
+		if _, ok := m.Unknown[i]; ok {
+			fmt.Fprintf(out, "  class m%d unknown\n", i)
+		}
+	}
+	return out.Flush()
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestMermaid(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "mermaid")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `graph TD
+  classDef root stroke-width:3px,font-weight:bold
+  classDef unknown stroke-dasharray:5 5
+  m0["example.com/app"]
+  m1["example.com/a@v1.0.0"]
+  m2["example.com/b@v1.0.0"]
+  m3["example.com/c@v1.0.0"]
+  m4["example.com/b@v1.1.0"]
+  m5["example.com/missing@v1.0.0"]
+  m0 --> m1
+  m0 --> m4
+  m0 --> m5
+  m1 --> m2
+  m1 --> m3
+  m2 --> m3
+  m4 --> m3
+  class m0 root
+  class m5 unknown
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
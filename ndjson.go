@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// ndjsonRecord is a line of the ndjson format. The first line is always a
+// header carrying the schema version, and the last an end record counting
+// what came before, so a consumer can tell a complete stream from one cut
+// short.
+type ndjsonRecord struct {
+	// Type is one of header, module, edge, unknown, error or end.
+	Type          string `json:"type"`
+	SchemaVersion int    `json:"schemaVersion,omitempty"`
+	Module        string `json:"module,omitempty"`
+	From          string `json:"from,omitempty"`
+	To            string `json:"to,omitempty"`
+	Indirect      bool   `json:"indirect,omitempty"`
+	Error         string `json:"error,omitempty"`
+	Modules       int    `json:"modules,omitempty"`
+	Edges         int    `json:"edges,omitempty"`
+}
+
+// ndjsonWriter writes the ndjson format, one record per line, each module
+// and edge only once however often it's seen.
+type ndjsonWriter struct {
+	w       io.Writer
+	enc     *json.Encoder
+	err     error
+	started bool
+	modules map[string]bool
+	edges   map[[2]string]bool
+}
+
+// ndjsonStream is the writer streaming the graph while it's built, nil if it
+// isn't. The ndjson format finishes the stream rather than starting again.
+var ndjsonStream *ndjsonWriter
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	return &ndjsonWriter{
+		w:       w,
+		enc:     json.NewEncoder(w),
+		modules: make(map[string]bool),
+		edges:   make(map[[2]string]bool),
+	}
+}
+
+// write writes record, the header first if it hasn't been written yet. The
+// first error is kept and every later record dropped.
+func (nd *ndjsonWriter) write(record ndjsonRecord) {
+	if nd.err != nil {
+		return
+	}
+	if !nd.started {
+		nd.started = true
+		nd.write(ndjsonRecord{Type: "header", SchemaVersion: jsonSchemaVersion})
+	}
+	nd.err = nd.enc.Encode(record)
+}
+
+// module writes a module record for name unless one has been written.
+func (nd *ndjsonWriter) module(name string) {
+	if nd.modules[name] {
+		return
+	}
+	nd.modules[name] = true
+	nd.write(ndjsonRecord{Type: "module", Module: name})
+}
+
+// edge writes an edge record for from requiring to unless one has been
+// written, after the records of both modules.
+func (nd *ndjsonWriter) edge(from, to string, indirect bool) {
+	key := [2]string{from, to}
+	if nd.edges[key] {
+		return
+	}
+	nd.module(from)
+	nd.module(to)
+	nd.edges[key] = true
+	nd.write(ndjsonRecord{Type: "edge", From: from, To: to, Indirect: indirect})
+}
+
+// visit records a module and its requirements as the walk reads them, to be
+// passed to deptree.WithVisit.
+func (nd *ndjsonWriter) visit(name string, requires []deptree.Require) {
+	nd.module(name)
+	for _, require := range requires {
+		nd.edge(name, require.Name(), require.Indirect)
+	}
+}
+
+// finish writes whatever of the graph wasn't streamed while it was built,
+// which is all of it if nothing was, followed by the modules that couldn't
+// be resolved or read and the end record.
+func (nd *ndjsonWriter) finish(m *module) error {
+	for _, name := range m.Indexes {
+		nd.module(name)
+	}
+	for parent := range m.Indexes {
+		for _, child := range m.Packages[parent] {
+			_, indirect := m.Indirect[deptree.Edge{From: parent, To: child}]
+			nd.edge(m.Indexes[parent], m.Indexes[child], indirect)
+		}
+	}
+	for i, name := range m.Indexes {
+		if _, ok := m.Unknown[i]; ok {
+			nd.write(ndjsonRecord{Type: "unknown", Module: name})
+		}
+	}
+	for _, e := range m.moduleErrors() {
+		nd.write(ndjsonRecord{Type: "error", Module: e.Module, Error: e.Error})
+	}
+	nd.write(ndjsonRecord{Type: "end", Modules: len(nd.modules), Edges: len(nd.edges)})
+	return nd.err
+}
+
+// ndjsonFormat is newline delimited JSON, a record per module and per edge,
+// which consumers can process a line at a time rather than holding the
+// whole graph in memory.
+type ndjsonFormat struct{}
+
+func (ndjsonFormat) write(w io.Writer, m *module, depth int) error {
+	nd := ndjsonStream
+	if nd == nil || nd.w != w {
+		nd = newNDJSONWriter(w)
+	}
+	return nd.finish(m)
+}
+
+func (ndjsonFormat) extension() string {
+	return ".ndjson"
+}
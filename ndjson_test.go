@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// readNDJSON parses the records of the ndjson format, one per line.
+func readNDJSON(t *testing.T, output string) []ndjsonRecord {
+	t.Helper()
+	records := make([]ndjsonRecord, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		var record ndjsonRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q isn't JSON: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNDJSON(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "ndjson")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	// Records are written as the walk reads each go.mod, so each module's
+	// requires come before those of the modules it requires.
+	want := `{"type":"header","schemaVersion":1}
+{"type":"module","module":"example.com/app"}
+{"type":"module","module":"example.com/a v1.0.0"}
+{"type":"edge","from":"example.com/app","to":"example.com/a v1.0.0"}
+{"type":"module","module":"example.com/b v1.1.0"}
+{"type":"edge","from":"example.com/app","to":"example.com/b v1.1.0","indirect":true}
+{"type":"module","module":"example.com/missing v1.0.0"}
+{"type":"edge","from":"example.com/app","to":"example.com/missing v1.0.0"}
+{"type":"module","module":"example.com/b v1.0.0"}
+{"type":"edge","from":"example.com/a v1.0.0","to":"example.com/b v1.0.0"}
+{"type":"module","module":"example.com/c v1.0.0"}
+{"type":"edge","from":"example.com/a v1.0.0","to":"example.com/c v1.0.0"}
+{"type":"edge","from":"example.com/b v1.0.0","to":"example.com/c v1.0.0"}
+{"type":"edge","from":"example.com/b v1.1.0","to":"example.com/c v1.0.0"}
+{"type":"unknown","module":"example.com/missing v1.0.0"}
+{"type":"end","modules":6,"edges":7}
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestNDJSONNotStreamed(t *testing.T) {
+	streamed, _, _ := run(t, "example.com/app", "-format", "ndjson")
+	// -redact rewrites the graph once it's built, so nothing is streamed.
+	// No module matches its prefix, so the records are the same.
+	written, stderr, code := run(t, "example.com/app", "-format", "ndjson", "-redact", "example.com/none")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	sorted := func(records []ndjsonRecord) []ndjsonRecord {
+		sort.SliceStable(records, func(a, b int) bool {
+			return records[a].Type+records[a].Module+records[a].From+records[a].To < records[b].Type+records[b].Module+records[b].From+records[b].To
+		})
+		return records
+	}
+	if got, want := sorted(readNDJSON(t, written)), sorted(readNDJSON(t, streamed)); !reflect.DeepEqual(got, want) {
+		t.Errorf("records = %+v, want the same records as streamed, %+v", got, want)
+	}
+}
+
+func TestNDJSONPrefix(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "ndjson", "-prefix", "example.com/b")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	records := readNDJSON(t, stdout)
+	if end := records[len(records)-1]; end.Type != "end" || end.Modules != 2 || end.Edges != 1 {
+		t.Errorf("end = %+v, want the root and example.com/b v1.1.0 with the require between them", end)
+	}
+}
+
+func TestNDJSONPackages(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/imports", "-format", "ndjson", "-granularity", "package")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	// The package graph is only known once the module graph is built, so is
+	// written rather than streamed.
+	want := `{"type":"header","schemaVersion":1}
+{"type":"module","module":"example.com/imports"}
+{"type":"module","module":"example.com/imports/internal/x"}
+{"type":"module","module":"example.com/c v1.0.0"}
+{"type":"edge","from":"example.com/imports","to":"example.com/imports/internal/x"}
+{"type":"edge","from":"example.com/imports/internal/x","to":"example.com/c v1.0.0"}
+{"type":"end","modules":3,"edges":2}
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+type opmlDocument struct {
+	XMLName xml.Name      `xml:"opml"`
+	Version string        `xml:"version,attr"`
+	Title   string        `xml:"head>title"`
+	Body    []opmlOutline `xml:"body>outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Version  string        `xml:"version,attr,omitempty"`
+	License  string        `xml:"license,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// writeOPML writes the graph as an OPML outline. Each module appears once,
+// nested under the module it was first reached from by a depth first walk
+// from the root. With -licenses each outline has a license attribute.
+func writeOPML(w io.Writer, m *module) error {
+	visited := make(map[int]bool)
+	var outline func(i int) opmlOutline
+	outline = func(i int) opmlOutline {
+		visited[i] = true
+		modPath, version := deptree.SplitModuleName(m.Indexes[i])
+		node := opmlOutline{
+			Text:    modPath,
+			Version: version,
+			License: m.licenses[i],
+		}
+		for _, child := range m.Packages[i] {
+			if !visited[child] {
+				node.Outlines = append(node.Outlines, outline(child))
+			}
+		}
+		return node
+	}
+
+	document := opmlDocument{
+		Version: "2.0",
+		Title:   m.Indexes[0],
+		Body:    []opmlOutline{outline(0)},
+	}
+	b, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, xml.Header+string(b))
+	return err
+}
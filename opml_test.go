@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestOPML(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "opml")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	// Each module appears once, under the first module reaching it.
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="2.0">
+  <head>
+    <title>example.com/app</title>
+  </head>
+  <body>
+    <outline text="example.com/app">
+      <outline text="example.com/a" version="v1.0.0">
+        <outline text="example.com/b" version="v1.0.0">
+          <outline text="example.com/c" version="v1.0.0"></outline>
+        </outline>
+      </outline>
+      <outline text="example.com/b" version="v1.1.0"></outline>
+      <outline text="example.com/missing" version="v1.0.0"></outline>
+    </outline>
+  </body>
+</opml>
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+	gomodule "golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// outdatedModule is a module path whose selected version has newer releases
+// on the module proxy, or that its authors have retracted or deprecated.
+type outdatedModule struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+	// Patch is the newest release with the same major and minor version.
+	Patch string `json:"patch,omitempty"`
+	// Minor is the newest release with the same major version, if it has a
+	// later minor version.
+	Minor string `json:"minor,omitempty"`
+	// Major is the newest release of a later major version, along with its
+	// module path, such as example.com/x/v2 v2.1.0.
+	Major string `json:"major,omitempty"`
+	// Retracted holds the newer versions skipped over as retracted.
+	Retracted []string `json:"retracted,omitempty"`
+	// CurrentRetracted is set if the selected version itself is retracted.
+	CurrentRetracted bool `json:"currentRetracted,omitempty"`
+	// Deprecated is the deprecation message of the module, from the go.mod
+	// of its latest version.
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
+// moduleReleases is what the module proxy knows about the releases of a
+// module path.
+type moduleReleases struct {
+	versions   []string
+	retracts   []deptree.Retraction
+	deprecated string
+}
+
+// retracted reports whether version is retracted.
+func (r moduleReleases) retracted(version string) bool {
+	for _, retract := range r.retracts {
+		if retract.Contains(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// newest returns the highest version accepted by match that isn't
+// retracted, adding those skipped for being retracted to skipped. Pre-releases
+// are only considered if pre is set.
+func (r moduleReleases) newest(match func(string) bool, pre bool, skipped map[string]bool) string {
+	for i := len(r.versions) - 1; i >= 0; i-- {
+		version := r.versions[i]
+		if !match(version) || (!pre && semver.Prerelease(version) != "") {
+			continue
+		}
+		if r.retracted(version) {
+			skipped[version] = true
+			continue
+		}
+		return version
+	}
+	return ""
+}
+
+// readReleases lists the versions of modPath on the module proxy, reading
+// which of them are retracted, and whether the module is deprecated, from the
+// go.mod of the latest, as the go command does.
+func readReleases(o deptree.Options, modPath string) (moduleReleases, error) {
+	versions, err := o.ProxyVersions(modPath)
+	if err != nil || len(versions) == 0 {
+		return moduleReleases{}, err
+	}
+	releases := moduleReleases{versions: versions}
+	// A latest version whose go.mod can't be read still has the versions
+	// listed, just without retractions or deprecation.
+	if mod, err := o.ReadGoMod(modPath, versions[len(versions)-1]); err == nil {
+		releases.retracts = mod.Retracts
+		releases.deprecated = mod.Deprecated
+	}
+	return releases, nil
+}
+
+// majorPath returns the module path of major version n of the module whose
+// path without its major version suffix is prefix.
+func majorPath(prefix string, n int) string {
+	if strings.HasPrefix(prefix, "gopkg.in/") {
+		return prefix + ".v" + strconv.Itoa(n)
+	}
+	return prefix + "/v" + strconv.Itoa(n)
+}
+
+// checkOutdated compares the selected version of modPath with its releases on
+// the module proxy, returning nil if it's up to date.
+func checkOutdated(o deptree.Options, modPath, version string) (*outdatedModule, error) {
+	releases, err := readReleases(o, modPath)
+	if err != nil {
+		return nil, err
+	}
+	pre := semver.Prerelease(version) != ""
+	skipped := make(map[string]bool)
+	newer := func(match func(string) bool) func(string) bool {
+		return func(v string) bool {
+			return semver.Compare(v, version) > 0 && match(v)
+		}
+	}
+	outdated := outdatedModule{
+		Module:           modPath,
+		Version:          version,
+		CurrentRetracted: releases.retracted(version),
+		Deprecated:       releases.deprecated,
+	}
+	outdated.Patch = releases.newest(newer(func(v string) bool {
+		return semver.MajorMinor(v) == semver.MajorMinor(version)
+	}), pre, skipped)
+	outdated.Minor = releases.newest(newer(func(v string) bool {
+		return semver.Major(v) == semver.Major(version) && semver.MajorMinor(v) != semver.MajorMinor(version)
+	}), pre, skipped)
+
+	// Later major versions are different module paths, so ask about each in
+	// turn until one isn't on the proxy.
+	if prefix, _, ok := gomodule.SplitPathVersion(modPath); ok {
+		major, _ := strconv.Atoi(strings.TrimPrefix(semver.Major(version), "v"))
+		if major < 1 {
+			major = 1
+		}
+		for n := major + 1; ; n++ {
+			nextPath := majorPath(prefix, n)
+			next, err := readReleases(o, nextPath)
+			if err != nil {
+				return nil, err
+			}
+			if len(next.versions) == 0 {
+				break
+			}
+			// Versions skipped in another major version belong to another
+			// module path, so aren't reported.
+			if v := next.newest(func(string) bool { return true }, pre, make(map[string]bool)); v != "" {
+				outdated.Major = nextPath + " " + v
+			}
+		}
+	}
+
+	for _, v := range releases.versions {
+		if skipped[v] {
+			outdated.Retracted = append(outdated.Retracted, v)
+		}
+	}
+	if outdated.Patch == "" && outdated.Minor == "" && outdated.Major == "" && !outdated.CurrentRetracted && outdated.Deprecated == "" {
+		return nil, nil
+	}
+	return &outdated, nil
+}
+
+// readOutdated asks the module proxy about the releases of every module path
+// in the graph, at most jobs at a time, and records those with newer versions
+// than minimal version selection picks. Modules replaced with a directory are
+// skipped, as their version says nothing about the code built.
+func (m *module) readOutdated(jobs int) error {
+	resolved := m.resolvedVersions()
+	results := make([]*outdatedModule, len(resolved))
+	errs := make([]error, len(resolved))
+	limit := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for pos, r := range resolved {
+		if i, ok := m.Lookup[r.Module+" "+r.Selected]; ok {
+			if replace, ok := m.Replaced[i]; ok && replace.NewVersion == "" {
+				continue
+			}
+		}
+		wg.Add(1)
+		go func(pos int, r resolvedVersion) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			results[pos], errs[pos] = checkOutdated(m.Options(), r.Module, r.Selected)
+		}(pos, r)
+	}
+	wg.Wait()
+
+	m.outdated = make([]outdatedModule, 0)
+	for pos, result := range results {
+		if errs[pos] != nil {
+			return fmt.Errorf("checking %s for newer versions: %v", resolved[pos].Module, errs[pos])
+		}
+		if result != nil {
+			m.outdated = append(m.outdated, *result)
+		}
+	}
+	return nil
+}
+
+// printOutdated writes a table of the modules with newer versions available,
+// noting those retracted or deprecated.
+func printOutdated(w io.Writer, m *module) {
+	fmt.Fprintln(w, "Outdated modules:")
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  MODULE\tVERSION\tPATCH\tMINOR\tMAJOR\tNOTES")
+	for _, o := range m.outdated {
+		notes := make([]string, 0)
+		if o.CurrentRetracted {
+			notes = append(notes, "retracted")
+		}
+		if len(o.Retracted) > 0 {
+			notes = append(notes, "skipped retracted "+strings.Join(o.Retracted, ", "))
+		}
+		if o.Deprecated != "" {
+			notes = append(notes, "deprecated: "+o.Deprecated)
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", o.Module, o.Version, orDash(o.Patch), orDash(o.Minor), orDash(o.Major), strings.Join(notes, "; "))
+	}
+	tw.Flush()
+}
+
+// orDash returns s, or a dash if it's empty, so table columns are never
+// blank.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
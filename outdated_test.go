@@ -0,0 +1,85 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestCheckOutdated(t *testing.T) {
+	files := map[string]string{
+		"/example.com/x/@v/list":             "v1.0.0\nv1.0.1\nv1.0.2\nv1.1.0\nv1.2.0\nv1.3.0-rc.1\n",
+		"/example.com/x/@v/v1.3.0-rc.1.mod":  "// Deprecated: use example.com/y instead.\nmodule example.com/x\n\nretract v1.0.2\n",
+		"/example.com/x/v2/@v/list":          "v2.0.0\nv2.1.0\n",
+		"/example.com/x/v2/@v/v2.1.0.mod":    "module example.com/x/v2\n",
+		"/example.com/current/@v/list":       "v1.0.0\n",
+		"/example.com/current/@v/v1.0.0.mod": "module example.com/current\n",
+		"/example.com/pulled/@v/list":        "v1.0.0\n",
+		"/example.com/pulled/@v/v1.0.0.mod":  "module example.com/pulled\n\nretract v1.0.0 // broken\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	o := deptree.NewGraph(
+		deptree.WithGopath(t.TempDir()),
+		deptree.WithProxy(server.URL),
+		deptree.WithProxyCache(t.TempDir()),
+	).Options()
+
+	tests := []struct {
+		modPath string
+		version string
+		want    *outdatedModule
+	}{
+		{
+			modPath: "example.com/x",
+			version: "v1.0.0",
+			want: &outdatedModule{
+				Module:     "example.com/x",
+				Version:    "v1.0.0",
+				Patch:      "v1.0.1",
+				Minor:      "v1.2.0",
+				Major:      "example.com/x/v2 v2.1.0",
+				Retracted:  []string{"v1.0.2"},
+				Deprecated: "use example.com/y instead.",
+			},
+		},
+		{
+			modPath: "example.com/x",
+			version: "v1.3.0-rc.1",
+			want: &outdatedModule{
+				Module:     "example.com/x",
+				Version:    "v1.3.0-rc.1",
+				Major:      "example.com/x/v2 v2.1.0",
+				Deprecated: "use example.com/y instead.",
+			},
+		},
+		{
+			modPath: "example.com/pulled",
+			version: "v1.0.0",
+			want:    &outdatedModule{Module: "example.com/pulled", Version: "v1.0.0", CurrentRetracted: true},
+		},
+		{modPath: "example.com/current", version: "v1.0.0"},
+		{modPath: "example.com/missing", version: "v1.0.0"},
+	}
+	for _, test := range tests {
+		t.Run(test.modPath+"@"+test.version, func(t *testing.T) {
+			got, err := checkOutdated(o, test.modPath, test.version)
+			if err != nil {
+				t.Fatalf("checkOutdated() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("checkOutdated() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
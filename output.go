@@ -0,0 +1,153 @@
+package main
+
+import (
+	"io"
+	"os"
+	"sort"
+)
+
+// outputFormat is a way of writing the graph out. Adding a format only means
+// adding it to formats.
+type outputFormat interface {
+	// write writes the graph. Formats that print a tree stop at the given
+	// depth, a negative depth meaning no limit.
+	write(w io.Writer, m *module, depth int) error
+	// extension is the file extension of files written in the format.
+	extension() string
+}
+
+// graphFormat is an output format that always writes the whole graph.
+type graphFormat struct {
+	writeGraph func(w io.Writer, m *module) error
+	ext        string
+}
+
+func (f graphFormat) write(w io.Writer, m *module, depth int) error {
+	return f.writeGraph(w, m)
+}
+
+func (f graphFormat) extension() string {
+	return f.ext
+}
+
+// textFormat is the indented tree, followed by the reports enabled on the
+// command line.
+type textFormat struct{}
+
+func (textFormat) write(w io.Writer, m *module, depth int) error {
+	if *directOnly {
+		printDirectRequires(w, m)
+	} else {
+		m.printTree(w, 0, "", depth)
+	}
+	printCycles(w, m)
+	printDeprecated(w, m)
+	printPublishTimes(w, m)
+	if *publishedAfter != "" {
+		printRecentlyPublished(w, m, publishedAfterTime)
+	}
+	if *downgradeRisk {
+		printDowngradeRisks(w, m)
+	}
+	if *resolved {
+		printResolvedVersions(w, m)
+	}
+	if *showConflicts {
+		printConflicts(w, "Module conflicts:", m.conflicts())
+	}
+	if m.sums != nil {
+		printSumVerification(w, "", m.sums)
+	}
+	if m.outdated != nil {
+		printOutdated(w, m)
+	}
+	if *vuln {
+		printVulnerabilities(w, "Vulnerabilities:", m.vulns)
+	}
+	if m.licenses != nil {
+		printLicenses(w, m)
+	}
+	if *suspectIndirect {
+		printSuspectIndirect(w, m)
+	}
+	if *strictSemver {
+		printInvalidVersions(w, m)
+	}
+	if *coveringSet {
+		printCoveringSet(w, m)
+	}
+	printCoalesced(w, m)
+	if *packages {
+		printPackageImports(w, m)
+	}
+	if *showDepths {
+		printDepths(w, m)
+	}
+	if *showStats {
+		printStats(w, m)
+	}
+	if *showGraphStats {
+		printGraphStats(w, m)
+	}
+	if *unusedReplaces {
+		printUnusedReplaces(w, m)
+	}
+	return nil
+}
+
+func (textFormat) extension() string {
+	return ".txt"
+}
+
+// formats holds every output format by the name -format selects it with.
+var formats = map[string]outputFormat{
+	"text":             textFormat{},
+	"tree":             boxTreeFormat{},
+	"json":             graphFormat{writeGraph: writeJSON, ext: ".json"},
+	"ndjson":           ndjsonFormat{},
+	"arrows":           graphFormat{writeGraph: writeArrows, ext: ".json"},
+	"cypher":           graphFormat{writeGraph: writeCypher, ext: ".cypher"},
+	"dot":              graphFormat{writeGraph: writeDOT, ext: ".dot"},
+	"tf-dot":           graphFormat{writeGraph: writeTerraformDOT, ext: ".dot"},
+	"svg":              graphFormat{writeGraph: writeSVG, ext: ".svg"},
+	"dependency-track": graphFormat{writeGraph: writeDependencyTrack, ext: ".json"},
+	"cyclonedx":        graphFormat{writeGraph: writeDependencyTrack, ext: ".cdx.json"},
+	"spdx":             graphFormat{writeGraph: writeSPDX, ext: ".spdx.json"},
+	"opml":             graphFormat{writeGraph: writeOPML, ext: ".opml"},
+	"pajek":            graphFormat{writeGraph: writePajek, ext: ".net"},
+	"mermaid":          graphFormat{writeGraph: writeMermaid, ext: ".mmd"},
+	"toposort":         graphFormat{writeGraph: writeToposort, ext: ".txt"},
+}
+
+// formatNames returns the names of every output format in order.
+func formatNames() []string {
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeGraph writes the graph in the named output format, which must be one
+// of formats.
+func writeGraph(w io.Writer, format string, m *module, depth int) error {
+	return formats[format].write(w, m, depth)
+}
+
+// openOutput returns the writer to write output to, which is stdout unless
+// filePath is set, and both stdout and the file if tee is also set. The
+// returned function closes the file.
+func openOutput(filePath string, tee bool) (io.Writer, func() error, error) {
+	if filePath == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tee {
+		return io.MultiWriter(os.Stdout, file), file.Close, nil
+	}
+	return file, file.Close, nil
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// packageImport is an import of a package belonging to a module in the graph
+// by a package of the root module, or of a module it imports packages of.
+// PackageModule owns the importing package and Module the one imported.
+type packageImport struct {
+	Package       string `json:"package"`
+	PackageModule string `json:"packageModule"`
+	Import        string `json:"import"`
+	Module        string `json:"module"`
+}
+
+// skippedFile is a Go file whose imports couldn't be read, named by the
+// package it's in, so its imports are missing from the package imports.
+type skippedFile struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// readPackageImports parses the imports of every Go file in the root module
+// and records those of packages belonging to a module in the graph, along
+// with every package of the root module. Only the import blocks are parsed,
+// but every file is still read, so this is much slower than building the
+// module graph alone on large modules. Files the go command wouldn't build
+// for the current GOOS and GOARCH, going by their names and build
+// constraints, are left out, as go/build decides. Files that can't be parsed
+// are skipped and recorded. If deps is set, the imports of the dependency
+// packages imported are followed too, as far as their source is on disk.
+//
+// golang.org/x/tools/go/packages isn't used, as it runs go list, which loads
+// the module graph with the go command and downloads whatever's missing from
+// it. The tool is built to work from the files already on disk without the
+// go command, so -packages works offline and on trees the go command can't
+// load, like the rest of the tool.
+func (m *module) readPackageImports(deps bool) error {
+	root, err := deptree.ParseGoMod(path.Join(m.Dir, "go.mod"))
+	if err != nil {
+		return err
+	}
+
+	imports := make([]packageImport, 0)
+	m.skippedFiles = make([]skippedFile, 0)
+	rootPackages := make(map[string]bool)
+	fset := token.NewFileSet()
+	err = filepath.Walk(m.Dir, func(current string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if current == m.Dir {
+				return nil
+			}
+			if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+				return filepath.SkipDir
+			}
+			// Nested modules have their own graph.
+			if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(current, ".go") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.Dir, filepath.Dir(current))
+		if err != nil {
+			return err
+		}
+		pkg := path.Join(root.Name, filepath.ToSlash(rel))
+		imported, ok := m.parseImports(fset, current, pkg)
+		if !ok {
+			return nil
+		}
+		rootPackages[pkg] = true
+		for _, imp := range imported {
+			if owner, ok := m.owningModule(imp); ok {
+				imports = append(imports, packageImport{
+					Package:       pkg,
+					PackageModule: m.Indexes[0],
+					Import:        imp,
+					Module:        m.Indexes[owner],
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if deps {
+		imports = append(imports, m.readDependencyImports(fset, imports)...)
+	}
+
+	sort.Slice(imports, func(a, b int) bool {
+		if imports[a].Package != imports[b].Package {
+			return imports[a].Package < imports[b].Package
+		}
+		return imports[a].Import < imports[b].Import
+	})
+	m.imports = imports[:0]
+	for i, imp := range imports {
+		if i == 0 || imp != imports[i-1] {
+			m.imports = append(m.imports, imp)
+		}
+	}
+	m.rootPackages = make([]string, 0, len(rootPackages))
+	for pkg := range rootPackages {
+		m.rootPackages = append(m.rootPackages, pkg)
+	}
+	sort.Strings(m.rootPackages)
+	return nil
+}
+
+// parseImports returns the packages imported by the Go file at file, in the
+// package pkg, and false if the go command wouldn't build the file for the
+// current GOOS and GOARCH. A file that can't be read or parsed is recorded
+// in skippedFiles and also returns false.
+func (m *module) parseImports(fset *token.FileSet, file, pkg string) ([]string, bool) {
+	name := pkg + "/" + filepath.Base(file)
+	skip := func(err error) ([]string, bool) {
+		m.skippedFiles = append(m.skippedFiles, skippedFile{File: name, Error: err.Error()})
+		return nil, false
+	}
+	if match, err := build.Default.MatchFile(filepath.Dir(file), filepath.Base(file)); err != nil {
+		return skip(err)
+	} else if !match {
+		return nil, false
+	}
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return skip(err)
+	}
+	parsed, err := parser.ParseFile(fset, name, src, parser.ImportsOnly)
+	if list, ok := err.(scanner.ErrorList); ok && len(list) > 0 {
+		return skip(fmt.Errorf("line %d: %s", list[0].Pos.Line, list[0].Msg))
+	} else if err != nil {
+		return skip(err)
+	}
+	imported := make([]string, 0, len(parsed.Imports))
+	for _, spec := range parsed.Imports {
+		imp, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			return skip(err)
+		}
+		imported = append(imported, imp)
+	}
+	return imported, true
+}
+
+// readDependencyImports follows the imports of the root module's packages
+// into the packages of other modules, parsing the imports of each package
+// reached in turn. Test files aren't parsed, as tests of dependencies are
+// never built. Packages whose source isn't on disk, such as those of modules
+// whose go.mod was fetched from the module proxy, are left unexplored.
+func (m *module) readDependencyImports(fset *token.FileSet, rootImports []packageImport) []packageImport {
+	imports := make([]packageImport, 0)
+	visited := make(map[string]bool)
+	queue := make([]string, 0)
+	for _, imp := range rootImports {
+		if imp.Module != m.Indexes[0] && !visited[imp.Import] {
+			visited[imp.Import] = true
+			queue = append(queue, imp.Import)
+		}
+	}
+	for len(queue) > 0 {
+		pkg := queue[0]
+		queue = queue[1:]
+		owner, _ := m.owningModule(pkg)
+		modDir, ok := m.ModuleDir(owner)
+		if !ok {
+			continue
+		}
+		modPath, _ := deptree.SplitModuleName(m.Indexes[owner])
+		dir := filepath.Join(modDir, filepath.FromSlash(strings.TrimPrefix(pkg, modPath)))
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, info := range files {
+			name := info.Name()
+			if info.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			imported, ok := m.parseImports(fset, filepath.Join(dir, name), pkg)
+			if !ok {
+				continue
+			}
+			for _, imp := range imported {
+				importOwner, ok := m.owningModule(imp)
+				if !ok {
+					continue
+				}
+				imports = append(imports, packageImport{
+					Package:       pkg,
+					PackageModule: m.Indexes[owner],
+					Import:        imp,
+					Module:        m.Indexes[importOwner],
+				})
+				if !visited[imp] {
+					visited[imp] = true
+					queue = append(queue, imp)
+				}
+			}
+		}
+	}
+	return imports
+}
+
+// packageGraph returns the import graph of the packages read with
+// readPackageImports, for -granularity package, so the graph formats write
+// packages where they'd otherwise write modules. Each package is named by its
+// import path and the version of the module owning it, and the root module
+// is the root, requiring each of its packages but the one at its own path,
+// which the root stands for.
+func (m *module) packageGraph() *module {
+	sub := newModule(deptree.NewGraph(deptree.WithOptions(m.Options())))
+	sub.Dir = m.Dir
+	sub.imports = m.imports
+	sub.rootPackages = m.rootPackages
+	sub.skippedFiles = m.skippedFiles
+
+	name := func(pkg, owner string) string {
+		if owner == m.Indexes[0] {
+			return pkg
+		}
+		_, version := deptree.SplitModuleName(owner)
+		return strings.TrimSpace(pkg + " " + version)
+	}
+	// The root package imports what the root stands for, which it may also
+	// require as one of its packages, so each edge is only added once.
+	edges := make(map[[2]int]bool)
+	require := func(from, to int) {
+		if !edges[[2]int{from, to}] {
+			edges[[2]int{from, to}] = true
+			sub.Packages[from] = append(sub.Packages[from], to)
+		}
+	}
+	root := sub.Index(m.Indexes[0])
+	for _, pkg := range m.rootPackages {
+		if pkg != m.Indexes[0] {
+			require(root, sub.Index(pkg))
+		}
+	}
+	sub.RootRequires = len(sub.Packages[root])
+	for _, imp := range m.imports {
+		require(sub.Index(name(imp.Package, imp.PackageModule)), sub.Index(name(imp.Import, imp.Module)))
+	}
+	return sub
+}
+
+// owningModule returns the index of the module in the graph that provides
+// the package imported, preferring the longest matching module path as the
+// go command does.
+func (m *module) owningModule(imported string) (int, bool) {
+	owner, longest := -1, 0
+	for i, name := range m.Indexes {
+		modPath, _ := deptree.SplitModuleName(name)
+		if (imported == modPath || strings.HasPrefix(imported, modPath+"/")) && len(modPath) > longest {
+			owner, longest = i, len(modPath)
+		}
+	}
+	return owner, owner >= 0
+}
+
+// printPackageImports writes the imports of the root module's packages,
+// grouped by importing package.
+func printPackageImports(w io.Writer, m *module) {
+	fmt.Fprintln(w, "Package imports:")
+	for i, imp := range m.imports {
+		if i == 0 || imp.Package != m.imports[i-1].Package {
+			if imp.PackageModule == m.Indexes[0] {
+				fmt.Fprintln(w, "  "+imp.Package+":")
+			} else {
+				fmt.Fprintln(w, "  "+imp.Package+" ("+imp.PackageModule+"):")
+			}
+		}
+		fmt.Fprintln(w, "    "+imp.Import+" ("+imp.Module+")")
+	}
+	if len(m.skippedFiles) > 0 {
+		fmt.Fprintln(w, "Skipped files:")
+		for _, f := range m.skippedFiles {
+			fmt.Fprintln(w, "  "+f.File+": "+f.Error)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestGranularityPackage(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/imports", "-granularity", "package")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	// The root package stands for the root, so its import of internal/x is
+	// the root's require of it rather than a second one.
+	want := `example.com/imports:
+  example.com/imports/internal/x:
+    example.com/c v1.0.0
+Package imports:
+  example.com/imports:
+    example.com/imports/internal/x (example.com/imports)
+  example.com/imports/internal/x:
+    example.com/c (example.com/c v1.0.0)
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// writePajek writes the graph as a Pajek .net network, each vertex labelled
+// as in the dot format. Pajek numbers vertices from 1, so each module is
+// numbered one more than its index.
+func writePajek(w io.Writer, m *module) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "*Vertices %d\n", len(m.Indexes))
+	for i := range m.Indexes {
+		// Pajek labels have no escaping, so quotes can't appear in them.
+		fmt.Fprintf(out, "%d \"%s\"\n", i+1, strings.Replace(m.nodeLabel(i), "\"", "'", -1))
+	}
+	fmt.Fprintln(out, "*Arcs")
+	for i := range m.Indexes {
+		for _, child := range m.Packages[i] {
+			fmt.Fprintf(out, "%d %d\n", i+1, child+1)
+		}
+	}
+	return out.Flush()
+}
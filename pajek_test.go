@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestPajek(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "pajek")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `*Vertices 6
+1 "example.com/app"
+2 "example.com/a v1.0.0"
+3 "example.com/b v1.0.0"
+4 "example.com/c v1.0.0"
+5 "example.com/b v1.1.0"
+6 "example.com/missing v1.0.0"
+*Arcs
+1 2
+1 5
+1 6
+2 3
+2 4
+3 4
+5 4
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
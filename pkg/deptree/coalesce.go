@@ -0,0 +1,36 @@
+package deptree
+
+// CoalescedRequire is a requirement whose version was replaced by the version
+// the root module selects for the same module path.
+type CoalescedRequire struct {
+	Parent   string `json:"parent"`
+	Module   string `json:"module"`
+	Required string `json:"required"`
+	Selected string `json:"selected"`
+}
+
+// selectVersions records the version of each module path the root module
+// requires, which coalesce treats as the versions the build really uses.
+func (g *Graph) selectVersions(requires []Require) {
+	for _, require := range requires {
+		g.selected[require.Path] = require.Version
+	}
+}
+
+// coalesce returns require with its version replaced by the version the root
+// module selects for the same module path, recording the substitution if it
+// changed anything.
+func (g *Graph) coalesce(parent int, require Require) Require {
+	selected, ok := g.selected[require.Path]
+	if !ok || selected == require.Version {
+		return require
+	}
+	g.Coalesced = append(g.Coalesced, CoalescedRequire{
+		Parent:   g.Indexes[parent],
+		Module:   require.Path,
+		Required: require.Version,
+		Selected: selected,
+	})
+	require.Version = selected
+	return require
+}
@@ -0,0 +1,54 @@
+// Package deptree builds the dependency graph of a go module from the go.mod
+// files in the module cache, without running the go command.
+package deptree
+
+// Build builds the dependency graph of the module whose go.mod is in dir. An
+// error is only returned if the root module itself can't be read; modules
+// further down the graph that can't be found or read are recorded in the
+// graph's Unknown and Errors instead. If the context given with WithContext
+// is cancelled, the walk stops and the context's error is returned.
+func Build(dir string, opts ...Option) (*Graph, error) {
+	name, err := ModuleName(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := NewGraph(opts...)
+	g.Dir = dir
+	if g.opts.Vendor {
+		vendored, err := readVendorModules(dir)
+		if err != nil {
+			return nil, err
+		}
+		g.listVendor(name, vendored)
+	} else {
+		g.List(name, g.opts.MaxDepth)
+	}
+	return g.finish()
+}
+
+// finish waits for the walk to settle once it's over, saving the parse cache,
+// and returns the graph, or the error stopping the walk.
+func (g *Graph) finish() (*Graph, error) {
+	// Reads still going on in the background would race with saving the
+	// parse cache.
+	if g.prefetcher != nil {
+		g.prefetcher.wait()
+	}
+	if g.opts.ParseCache != "" {
+		loadParseCache(g.opts.ParseCache).save()
+	}
+	if err := g.opts.Context.Err(); err != nil {
+		return nil, err
+	}
+	if err, ok := g.Errors[0]; ok {
+		return nil, err
+	}
+	return g, nil
+}
+
+// deeper reports whether a walk limited to depth a goes at least as deep as
+// one limited to depth b, a negative depth meaning no limit.
+func deeper(a, b int) bool {
+	return a < 0 || (b >= 0 && a >= b)
+}
@@ -0,0 +1,209 @@
+package deptree
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// fixtureGopath returns the absolute path of the GOPATH under testdata that
+// every module the tests walk is resolved from.
+func fixtureGopath(t *testing.T) string {
+	t.Helper()
+	gopath, err := filepath.Abs(filepath.Join("testdata", "gopath"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The fixtures' module cache is the one in their GOPATH, whatever
+	// GOMODCACHE the tests are run with.
+	setenv(t, "GOMODCACHE", "")
+	return gopath
+}
+
+// setenv sets the environment variable key to value until the test ends.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+	prev, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// buildFixture builds the graph of testdata/app, resolving modules from
+// testdata/gopath.
+func buildFixture(t *testing.T, opts ...Option) (*Graph, error) {
+	t.Helper()
+	opts = append([]Option{WithGopath(fixtureGopath(t))}, opts...)
+	return Build(filepath.Join("testdata", "app"), opts...)
+}
+
+func TestBuild(t *testing.T) {
+	g, err := buildFixture(t)
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	wantIndexes := []string{
+		"example.com/app",
+		"example.com/a v1.0.0",
+		"example.com/b v1.0.0",
+		"example.com/c v1.0.0",
+		"example.com/b v1.1.0",
+		"example.com/local v0.0.0",
+		"example.com/missing v1.0.0",
+		"github.com/Upper/case v1.0.0",
+		"gopkg.in/Bar.v1 v1.0.0",
+		"gopkg.in/Foo.v2 v2.1.0",
+		"gopkg.in/yaml.v3 v3.0.1",
+	}
+	if !reflect.DeepEqual(g.Indexes, wantIndexes) {
+		t.Errorf("Indexes = %q, want %q", g.Indexes, wantIndexes)
+	}
+	index := func(name string) int {
+		i, ok := g.Lookup[name]
+		if !ok {
+			t.Fatalf("%s missing from the graph", name)
+		}
+		return i
+	}
+	if _, ok := g.Unknown[index("example.com/missing v1.0.0")]; !ok || len(g.Unknown) != 1 {
+		t.Errorf("Unknown = %v, want only example.com/missing", g.Unknown)
+	}
+	if _, ok := g.Indirect[Edge{From: 0, To: index("example.com/b v1.1.0")}]; !ok || len(g.Indirect) != 1 {
+		t.Errorf("Indirect = %v, want only the root's require of example.com/b", g.Indirect)
+	}
+	if got := g.Packages[index("example.com/local v0.0.0")]; !reflect.DeepEqual(got, []int{index("example.com/c v1.0.0")}) {
+		t.Errorf("example.com/local requires %v, want what ../local/go.mod requires", got)
+	}
+	// The capital is escaped in the module cache but not in GOPATH/src, and
+	// the dotted major version suffix is kept as it is in both.
+	if got := g.Packages[index("gopkg.in/Foo.v2 v2.1.0")]; !reflect.DeepEqual(got, []int{index("gopkg.in/yaml.v3 v3.0.1")}) {
+		t.Errorf("gopkg.in/Foo.v2 requires %v, want what its go.mod in the module cache requires", got)
+	}
+	if got, ok := g.Packages[index("gopkg.in/Bar.v1 v1.0.0")]; !ok || len(got) != 0 {
+		t.Errorf("gopkg.in/Bar.v1 requires %v, found %v, want it read from GOPATH/src", got, ok)
+	}
+	if got := g.Packages[index("github.com/Upper/case v1.0.0")]; !reflect.DeepEqual(got, []int{index("example.com/c v1.0.0")}) {
+		t.Errorf("github.com/Upper/case requires %v, want what its go.mod in the module cache requires", got)
+	}
+	if r, ok := g.Replaced[index("example.com/local v0.0.0")]; !ok || r.New != "../local" || len(g.Replaced) != 1 {
+		t.Errorf("Replaced = %v, want only example.com/local replaced by ../local", g.Replaced)
+	}
+	if len(g.Cycles) == 0 {
+		t.Error("Cycles is empty, want the cycle between example.com/a and example.com/c")
+	}
+	if len(g.Errors) != 0 {
+		t.Errorf("Errors = %v, want none", g.Errors)
+	}
+}
+
+func TestBuildCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, jobs := range []int{1, 8} {
+		if _, err := buildFixture(t, WithContext(ctx), WithJobs(jobs)); err != context.Canceled {
+			t.Errorf("Build() with %d jobs error = %v, want %v", jobs, err, context.Canceled)
+		}
+	}
+}
+
+func TestBuildExcludes(t *testing.T) {
+	dir := t.TempDir()
+	gomod := `module example.com/root
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.1.0
+)
+
+exclude example.com/a v1.0.0
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte(gomod), 0644); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Build(dir, WithGopath(fixtureGopath(t)))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	a := g.Lookup["example.com/a v1.0.0"]
+	if _, ok := g.Excluded[a]; !ok || len(g.Excluded) != 1 {
+		t.Errorf("Excluded = %v, want only example.com/a", g.Excluded)
+	}
+	if _, ok := g.Packages[a]; ok {
+		t.Errorf("example.com/a requires %v, want it never read", g.Packages[a])
+	}
+	if _, ok := g.Lookup["example.com/c v1.0.0"]; ok {
+		t.Error("example.com/c is in the graph, want it left out with the excluded example.com/a requiring it")
+	}
+}
+
+func TestBuildFilters(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		// want is whether each module is in the graph.
+		want map[string]bool
+	}{
+		{
+			name: "include",
+			opts: []Option{WithIncludePaths("example.com/a,example.com/b")},
+			want: map[string]bool{"example.com/a v1.0.0": true, "example.com/b v1.0.0": true, "example.com/c v1.0.0": false, "gopkg.in/yaml.v3 v3.0.1": false},
+		},
+		{
+			name: "exclude",
+			opts: []Option{WithExcludePaths("example.com/c,gopkg.in")},
+			want: map[string]bool{"example.com/a v1.0.0": true, "example.com/c v1.0.0": false, "gopkg.in/Foo.v2 v2.1.0": false, "gopkg.in/yaml.v3 v3.0.1": false},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g, err := buildFixture(t, test.opts...)
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			for name, want := range test.want {
+				if _, got := g.Lookup[name]; got != want {
+					t.Errorf("%s in the graph = %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildPruneIndirect(t *testing.T) {
+	g, err := buildFixture(t, WithPruneIndirect(true))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	b, ok := g.Lookup["example.com/b v1.1.0"]
+	if !ok {
+		t.Fatal("example.com/b v1.1.0 missing from the graph, want it listed as a leaf")
+	}
+	if children := g.Packages[b]; len(children) != 0 {
+		t.Errorf("example.com/b v1.1.0 requires %v, want it left unwalked", children)
+	}
+	if children := g.Packages[g.Lookup["example.com/a v1.0.0"]]; len(children) == 0 {
+		t.Error("example.com/a v1.0.0 requires nothing, want it walked as it's required directly")
+	}
+}
+
+func TestBuildMaxDepth(t *testing.T) {
+	g, err := buildFixture(t, WithMaxDepth(1))
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	for i, children := range g.Packages {
+		if i != 0 && len(children) > 0 {
+			t.Errorf("%s requires %v, want nothing walked below the root's requires", g.Indexes[i], children)
+		}
+	}
+}
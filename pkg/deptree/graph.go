@@ -0,0 +1,719 @@
+package deptree
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+var errModuleNotFound = errors.New("module not found in GOPATH")
+
+// Edge is a requirement of one module by another, by their indexes.
+type Edge struct {
+	From, To int
+}
+
+// Graph is the dependency graph of a go project. Every module is stored once
+// in Indexes, as its path and version separated by a space, and Packages maps
+// a module's index to the indexes of the modules it requires. The first module
+// listed is the root of the graph, named by its path alone.
+type Graph struct {
+	// Dir is the directory holding the root module's go.mod.
+	Dir string
+
+	Indexes  []string
+	Lookup   map[string]int
+	Packages map[int][]int
+	// Indirect holds the requires marked // indirect.
+	Indirect map[Edge]struct{}
+
+	// Unknown holds the modules that couldn't be found, and Errors those
+	// whose go.mod couldn't be read.
+	Unknown    map[int]struct{}
+	Errors     map[int]error
+	Cycles     [][]int
+	SelfRefs   map[int]struct{}
+	Mismatches map[int]string
+
+	PublishTimes map[int]time.Time
+	Deprecated   map[int]string
+	// Metadata holds the go and toolchain directives of every module whose
+	// go.mod was read.
+	Metadata map[int]ModuleMetadata
+
+	Coalesced    []CoalescedRequire
+	RootRequires int
+	Replaces     []ReplaceDirective
+	// Replaced holds the replace directive applied to each replaced module,
+	// and Excluded the modules the root module excludes, which aren't
+	// walked.
+	Replaced map[int]ReplaceDirective
+	Excluded map[int]struct{}
+	// Excludes holds every module version the root module's go.mod
+	// excludes, as "path version", whether or not the graph reaches it.
+	Excludes map[string]struct{}
+
+	opts       Options
+	prefetcher *prefetcher
+	expanded   map[int]int
+	stack      []int
+	onStack    map[int]bool
+	selected   map[string]string
+	// work is the go.mod a workspace root is walked as, and workspace holds
+	// the path of every module the workspace uses. Both are nil outside a
+	// workspace.
+	work      *GoMod
+	workspace map[string]struct{}
+}
+
+// NewGraph returns an empty graph configured by opts.
+func NewGraph(opts ...Option) *Graph {
+	o := newOptions(opts...)
+	g := &Graph{
+		Indexes:    make([]string, 0),
+		Lookup:     make(map[string]int),
+		Packages:   make(map[int][]int),
+		Indirect:   make(map[Edge]struct{}),
+		Unknown:    make(map[int]struct{}),
+		Errors:     make(map[int]error),
+		SelfRefs:   make(map[int]struct{}),
+		Mismatches: make(map[int]string),
+
+		PublishTimes: make(map[int]time.Time),
+		Deprecated:   make(map[int]string),
+		Metadata:     make(map[int]ModuleMetadata),
+		Replaced:     make(map[int]ReplaceDirective),
+		Excluded:     make(map[int]struct{}),
+		Excludes:     make(map[string]struct{}),
+
+		opts:     o,
+		expanded: make(map[int]int),
+		onStack:  make(map[int]bool),
+		selected: make(map[string]string),
+	}
+	if o.Jobs > 1 {
+		g.prefetcher = newPrefetcher(g, o.Jobs)
+	}
+	return g
+}
+
+// Options returns the options the graph was built with.
+func (g *Graph) Options() Options {
+	return g.opts
+}
+
+// Index returns the index of the named module, adding it to the graph if it
+// hasn't been seen before.
+func (g *Graph) Index(name string) int {
+	if i, ok := g.Lookup[name]; ok {
+		return i
+	}
+	i := len(g.Indexes)
+	g.Indexes = append(g.Indexes, name)
+	g.Lookup[name] = i
+	return i
+}
+
+// List walks the go.mod of modPath and each of its requirements, recording
+// the require relationships in the graph. A negative depth means no limit.
+func (g *Graph) List(modPath string, depth int) int {
+	i := g.Index(strings.Split(modPath, " //")[0])
+
+	if g.onStack[i] {
+		g.recordCycle(i)
+		return i
+	}
+	// A module only needs walking again if we can now go deeper than last time.
+	if prev, ok := g.expanded[i]; ok && deeper(prev, depth) {
+		return i
+	}
+	// A cancelled walk leaves what's left unwalked, for Build to report.
+	if g.opts.Context.Err() != nil {
+		return i
+	}
+	g.expanded[i] = depth
+
+	if g.opts.PublishTimes {
+		if info, ok := g.opts.readModuleInfo(g.Indexes[i]); ok {
+			g.PublishTimes[i] = info.Time
+		}
+	}
+
+	if i != 0 {
+		if r, ok := g.Replacement(g.Indexes[i]); ok {
+			g.Replaced[i] = r
+		}
+	}
+	// Like the go command, never load a version the root module excludes.
+	if _, ok := g.Excludes[g.Indexes[i]]; ok {
+		g.Excluded[i] = struct{}{}
+		return i
+	}
+	if depth == 0 {
+		return i
+	}
+	if g.opts.Trace {
+		log.Printf("trace: resolving %s at depth %d", g.Indexes[i], len(g.stack))
+	}
+	var mod GoMod
+	var err error
+	replacedBy := ""
+	if i == 0 && g.work != nil {
+		mod = *g.work
+	} else if i == 0 && g.Dir != "" {
+		// The root is read from where it was found rather than GOPATH.
+		mod, err = g.opts.parseGoModFile(g.Dir)
+	} else {
+		mod, replacedBy, err = g.readRequirement(g.Indexes[i], modPath, g.opts)
+	}
+	if err == errModuleNotFound {
+		g.Unknown[i] = struct{}{}
+		return i
+	} else if err != nil {
+		g.Errors[i] = err
+		return i
+	}
+
+	if mod.Deprecated != "" {
+		g.Deprecated[i] = mod.Deprecated
+	}
+	g.Metadata[i] = ModuleMetadata{Go: mod.Go, Toolchain: mod.Toolchain}
+
+	name, _ := NameAndVersion(modPath)
+	// A module replaced by a fork may declare either the path it's required
+	// by or the fork's own path, so only a path matching neither is a
+	// mismatch.
+	if i != 0 && mod.Name != "" && mod.Name != name && mod.Name != replacedBy {
+		g.Mismatches[i] = mod.Name
+	}
+
+	if i == 0 {
+		g.RootRequires = len(mod.Requires)
+		g.Replaces = mod.Replaces
+		for _, exclude := range mod.Excludes {
+			g.Excludes[exclude] = struct{}{}
+		}
+		if g.opts.CoalesceVersions {
+			g.selectVersions(mod.Requires)
+		}
+	}
+
+	requires := make([]Require, 0, len(mod.Requires))
+	for _, require := range mod.Requires {
+		if !g.opts.follows(require.Path) {
+			continue
+		}
+		if _, ok := g.workspace[require.Path]; ok {
+			// A module of the workspace is the same module wherever it's
+			// required, with no version, as it is to the go command.
+			require.Version = ""
+		}
+		if i != 0 && g.opts.CoalesceVersions {
+			require = g.coalesce(i, require)
+		}
+		requires = append(requires, require)
+	}
+	if g.opts.Visit != nil {
+		g.opts.Visit(g.Indexes[i], requires)
+	}
+	depths := make([]int, len(requires))
+	for pos, require := range requires {
+		depths[pos] = depth - 1
+		if require.Indirect && g.opts.PruneIndirect {
+			// The module is still listed, but only walked if something
+			// requires it directly.
+			depths[pos] = 0
+		}
+	}
+	g.prefetch(requires, depths)
+
+	g.stack = append(g.stack, i)
+	g.onStack[i] = true
+
+	children := make([]int, 0, len(requires))
+	for pos, require := range requires {
+		if require.Path == mod.Name {
+			g.SelfRefs[i] = struct{}{}
+		}
+		child := g.List(require.Name(), depths[pos])
+		if require.Indirect {
+			g.Indirect[Edge{From: i, To: child}] = struct{}{}
+		}
+		children = append(children, child)
+	}
+	g.Packages[i] = children
+
+	g.stack = g.stack[:len(g.stack)-1]
+	delete(g.onStack, i)
+	return i
+}
+
+// readRequirement reads the go.mod of the required module modPath, stored in
+// Indexes as name, following any replacement the root module makes for it.
+// A replaced module keeps its place in the graph under the name it was
+// required by, but its requirements come from the replacement, whose module
+// path is also returned.
+func (g *Graph) readRequirement(name, modPath string, o Options) (GoMod, string, error) {
+	r, ok := g.Replacement(name)
+	if !ok {
+		mod, err := o.readGoMod(modPath)
+		return mod, "", err
+	}
+	if o.Trace {
+		log.Printf("trace:   replaced by %s", r.String())
+	}
+	if r.IsLocal() {
+		// Modules replaced by a local directory, often required at a
+		// placeholder v0.0.0, are read from that directory.
+		mod, err := o.parseGoModFile(r.LocalDir(g.Dir))
+		return mod, r.New, err
+	}
+	mod, err := o.readGoMod(r.New + " " + r.NewVersion)
+	return mod, r.New, err
+}
+
+// prefetch starts reading the go.mod of each of requires in the background,
+// so they have already been parsed by the time the walk reaches them. Only
+// those the walk will read are prefetched: not those it walks to a depth of
+// 0, those the root module excludes, or those already read. The walk itself
+// stays serial, so the graph comes out the same whatever the number of jobs.
+func (g *Graph) prefetch(requires []Require, depths []int) {
+	if g.prefetcher == nil {
+		return
+	}
+	names := make([]string, 0, len(requires))
+	for pos, require := range requires {
+		if depths[pos] == 0 {
+			continue
+		}
+		name := require.Name()
+		if _, ok := g.Excludes[name]; ok {
+			continue
+		}
+		if i, ok := g.Lookup[name]; ok {
+			if prev, ok := g.expanded[i]; ok && prev != 0 {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	g.prefetcher.add(names)
+}
+
+// recordCycle records the cycle closed by revisiting i, which must be on the
+// current stack.
+func (g *Graph) recordCycle(i int) {
+	for pos := len(g.stack) - 1; pos >= 0; pos-- {
+		if g.stack[pos] == i {
+			cycle := make([]int, 0, len(g.stack)-pos+1)
+			cycle = append(cycle, g.stack[pos:]...)
+			g.Cycles = append(g.Cycles, append(cycle, i))
+			return
+		}
+	}
+}
+
+// Levels is the result of walking a graph breadth first from the root.
+type Levels struct {
+	// Order lists every module reached, in the order it was reached.
+	Order []int
+	// Depths holds the fewest requires separating each module from the
+	// root, the root being at depth 0.
+	Depths map[int]int
+	// Parents holds, for each module, every module requiring it from one
+	// level closer to the root, in the order they were reached.
+	Parents map[int][]int
+}
+
+// WalkLevels walks the graph breadth first from the root, a level at a time.
+// If stop isn't nil, the walk stops after the first level holding a module
+// stop returns true for.
+func (g *Graph) WalkLevels(stop func(i int) bool) Levels {
+	levels := Levels{
+		Order:   []int{0},
+		Depths:  map[int]int{0: 0},
+		Parents: make(map[int][]int),
+	}
+	level := []int{0}
+	for len(level) > 0 {
+		next := make([]int, 0)
+		for _, i := range level {
+			for _, child := range g.Packages[i] {
+				if depth, seen := levels.Depths[child]; !seen {
+					levels.Depths[child] = levels.Depths[i] + 1
+					next = append(next, child)
+				} else if depth != levels.Depths[i]+1 {
+					continue
+				}
+				levels.Parents[child] = append(levels.Parents[child], i)
+			}
+		}
+		levels.Order = append(levels.Order, next...)
+		if stop != nil {
+			for _, i := range next {
+				if stop(i) {
+					return levels
+				}
+			}
+		}
+		level = next
+	}
+	return levels
+}
+
+// Depths returns the fewest requires separating each module from the root,
+// the root being at depth 0.
+func (g *Graph) Depths() map[int]int {
+	return g.WalkLevels(nil).Depths
+}
+
+// SplitModuleName splits a module name as stored in Indexes into its module
+// path and version. The root module has no version.
+func SplitModuleName(name string) (string, string) {
+	fields := strings.Fields(name)
+	if len(fields) < 2 {
+		return name, ""
+	}
+	return fields[0], fields[1]
+}
+
+// ModuleMetadata is what a module's go.mod declares about the module itself.
+type ModuleMetadata struct {
+	Go        string `json:"go,omitempty"`
+	Toolchain string `json:"toolchain,omitempty"`
+}
+
+// GoMod holds the parts of a go.mod file the graph is built from.
+type GoMod struct {
+	Name       string
+	Deprecated string
+	Go         string
+	Toolchain  string
+	Requires   []Require
+	Replaces   []ReplaceDirective
+	// Excludes are the excluded module versions, as "path version".
+	Excludes []string
+	// Retracts are the versions of the module its authors retracted.
+	Retracts []Retraction
+}
+
+// Require is a require directive of a go.mod file. Indirect is set if it's
+// marked // indirect.
+type Require struct {
+	Path     string `json:"path"`
+	Version  string `json:"version,omitempty"`
+	Indirect bool   `json:"indirect,omitempty"`
+}
+
+// Name returns the name of the required module as stored in Indexes.
+func (r Require) Name() string {
+	return strings.TrimSpace(r.Path + " " + r.Version)
+}
+
+// parseRequire parses the body of a require directive, such as
+// "example.com/x v1.0.0 // indirect". Like the go command, it takes the
+// require to be indirect if its comment is "indirect", or starts with
+// "indirect;" to carry on with a note.
+func parseRequire(spec string) (Require, bool) {
+	spec = unquoteRequire(spec)
+	comment := ""
+	if pos := strings.Index(spec, "//"); pos >= 0 {
+		spec, comment = spec[:pos], strings.TrimSpace(spec[pos+2:])
+	}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return Require{}, false
+	}
+	r := Require{
+		Path:     fields[0],
+		Indirect: comment == "indirect" || strings.HasPrefix(comment, "indirect;"),
+	}
+	if len(fields) > 1 {
+		r.Version = fields[1]
+	}
+	return r, true
+}
+
+// Retraction is a retract directive of a go.mod file, retracting every
+// version from Low to High inclusive. Low and High are the same if a single
+// version is retracted.
+type Retraction struct {
+	Low  string `json:"low"`
+	High string `json:"high"`
+}
+
+// Contains reports whether version is retracted.
+func (r Retraction) Contains(version string) bool {
+	return semver.Compare(r.Low, version) <= 0 && semver.Compare(version, r.High) <= 0
+}
+
+// ReplaceDirective is a replace directive of a go.mod file. OldVersion is
+// empty if every version of Old is replaced, and NewVersion is empty if New
+// is a local directory.
+type ReplaceDirective struct {
+	Old        string `json:"old"`
+	OldVersion string `json:"oldVersion,omitempty"`
+	New        string `json:"new"`
+	NewVersion string `json:"newVersion,omitempty"`
+}
+
+// String returns the directive as it would be written in a go.mod file.
+func (r ReplaceDirective) String() string {
+	return strings.TrimSpace(r.Old+" "+r.OldVersion) + " => " + strings.TrimSpace(r.New+" "+r.NewVersion)
+}
+
+// parseReplace parses the body of a replace directive, such as
+// "example.com/x v1.0.0 => ../x".
+func parseReplace(spec string) (ReplaceDirective, bool) {
+	sides := strings.SplitN(strings.Split(spec, "//")[0], "=>", 2)
+	if len(sides) != 2 {
+		return ReplaceDirective{}, false
+	}
+	old, replacement := strings.Fields(sides[0]), strings.Fields(sides[1])
+	if len(old) == 0 || len(old) > 2 || len(replacement) == 0 || len(replacement) > 2 {
+		return ReplaceDirective{}, false
+	}
+	r := ReplaceDirective{Old: old[0], New: replacement[0]}
+	if len(old) == 2 {
+		r.OldVersion = old[1]
+	}
+	if len(replacement) == 2 {
+		r.NewVersion = replacement[1]
+	}
+	return r, true
+}
+
+// warnTooManyFiles warns that the process ran out of file descriptors, once.
+var warnTooManyFiles sync.Once
+
+// readGoMod reads the go.mod belonging to modPath, falling back to the module
+// proxy if it isn't present in GOPATH, and returning errModuleNotFound if it
+// can't be found either way.
+func (o Options) readGoMod(modPath string) (GoMod, error) {
+	rawPath, modFound := o.constructFilePath(EscapeCapitals(modPath))
+	if !modFound {
+		name, version := NameAndVersion(modPath)
+		return o.proxyGoMod(strings.TrimRight(name, "/"), version)
+	}
+	return o.parseModuleGoMod(rawPath)
+}
+
+// parsedGoMod is a go.mod that has been, or is being, parsed. done is closed
+// once mod and err are set.
+type parsedGoMod struct {
+	mod  GoMod
+	err  error
+	done chan struct{}
+}
+
+// goModCache holds the go.mod files parsed so far, keyed by file.
+type goModCache struct {
+	mu    sync.Mutex
+	files map[string]*parsedGoMod
+}
+
+func newGoModCache() *goModCache {
+	return &goModCache{files: make(map[string]*parsedGoMod)}
+}
+
+// immutableGoMods holds the go.mod files parsed from the module cache and the
+// proxy cache, which never change once written, so modules shared by several
+// graphs are only read once.
+var immutableGoMods = newGoModCache()
+
+// load returns the go.mod file parsed by load, only calling load the first
+// time file is asked for. It's safe to call concurrently, and waits for a
+// load of the same file that's already under way rather than starting
+// another. Errors that might go away by themselves aren't kept, so the file
+// is loaded again the next time it's asked for. A nil cache keeps nothing.
+func (c *goModCache) load(file string, load func() (GoMod, error)) (GoMod, error) {
+	if c == nil {
+		return load()
+	}
+	c.mu.Lock()
+	parsed, ok := c.files[file]
+	if !ok {
+		parsed = &parsedGoMod{done: make(chan struct{})}
+		c.files[file] = parsed
+	}
+	c.mu.Unlock()
+
+	if !ok {
+		parsed.mod, parsed.err = load()
+		if parsed.err != nil && retryable(parsed.err) {
+			c.mu.Lock()
+			delete(c.files, file)
+			c.mu.Unlock()
+		}
+		close(parsed.done)
+	}
+	<-parsed.done
+	return parsed.mod, parsed.err
+}
+
+// parseGoModFile reads and parses the go.mod in dir, which is outside the
+// module cache, so is only kept for as long as the graph is being built.
+func (o Options) parseGoModFile(dir string) (GoMod, error) {
+	file := path.Join(dir, "go.mod")
+	return o.mods.load(file, func() (GoMod, error) {
+		return ParseGoMod(file)
+	})
+}
+
+// ParseGoMod reads and parses the go.mod file at modFilePath, trying again a
+// few times if the process runs out of file descriptors.
+func ParseGoMod(modFilePath string) (GoMod, error) {
+	var fileBytes []byte
+	err := withRetry(context.Background(), func() error {
+		var readErr error
+		fileBytes, readErr = ioutil.ReadFile(modFilePath)
+		if tooManyFiles(readErr) {
+			warnTooManyFiles.Do(func() {
+				log.Println("warning: ran out of file descriptors, retrying go.mod reads after a back-off")
+			})
+		}
+		return readErr
+	})
+	if err != nil {
+		return GoMod{}, err
+	}
+	return parseGoModBytes(fileBytes), nil
+}
+
+// parseRetract parses the body of a retract directive, either a single
+// version or a closed interval written as "[low, high]".
+func parseRetract(line string) (Retraction, bool) {
+	line = strings.TrimSpace(strings.Split(line, "//")[0])
+	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+		bounds := strings.Split(strings.Trim(line, "[]"), ",")
+		if len(bounds) != 2 {
+			return Retraction{}, false
+		}
+		return Retraction{Low: strings.TrimSpace(bounds[0]), High: strings.TrimSpace(bounds[1])}, true
+	}
+	if line == "" {
+		return Retraction{}, false
+	}
+	return Retraction{Low: line, High: line}, true
+}
+
+// parseGoModBytes parses the contents of a go.mod file. Lines it doesn't
+// understand are skipped.
+func parseGoModBytes(fileBytes []byte) GoMod {
+	mod := GoMod{
+		Requires: make([]Require, 0),
+	}
+	block := ""
+	comments := make([]string, 0)
+
+	lines := strings.Split(string(fileBytes), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "//") {
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(line, "//")))
+			continue
+		}
+		if strings.HasPrefix(line, "module ") {
+			if mod.Name == "" {
+				parts := strings.SplitN(strings.TrimPrefix(line, "module "), "//", 2)
+				mod.Name = strings.Trim(strings.TrimSpace(parts[0]), "\"")
+				if len(parts) == 2 {
+					comments = append(comments, strings.TrimSpace(parts[1]))
+				}
+				mod.Deprecated = deprecationMessage(comments)
+			}
+		} else if strings.HasPrefix(line, "go ") && block == "" {
+			mod.Go = strings.TrimSpace(strings.Split(strings.TrimPrefix(line, "go "), "//")[0])
+		} else if strings.HasPrefix(line, "toolchain ") && block == "" {
+			mod.Toolchain = strings.TrimSpace(strings.Split(strings.TrimPrefix(line, "toolchain "), "//")[0])
+		} else if block != "" {
+			if line == ")" {
+				block = ""
+			} else if block == "require" {
+				if r, ok := parseRequire(line); ok {
+					mod.Requires = append(mod.Requires, r)
+				}
+			} else if block == "replace" {
+				if r, ok := parseReplace(line); ok {
+					mod.Replaces = append(mod.Replaces, r)
+				}
+			} else if block == "exclude" && line != "" {
+				mod.Excludes = append(mod.Excludes, parseExclude(line))
+			} else if block == "retract" {
+				if r, ok := parseRetract(line); ok {
+					mod.Retracts = append(mod.Retracts, r)
+				}
+			}
+		} else if line == "require (" {
+			block = "require"
+		} else if strings.HasPrefix(line, "require ") {
+			if r, ok := parseRequire(strings.TrimPrefix(line, "require ")); ok {
+				mod.Requires = append(mod.Requires, r)
+			}
+		} else if line == "replace (" {
+			block = "replace"
+		} else if strings.HasPrefix(line, "replace ") {
+			if r, ok := parseReplace(strings.TrimPrefix(line, "replace ")); ok {
+				mod.Replaces = append(mod.Replaces, r)
+			}
+		} else if line == "exclude (" {
+			block = "exclude"
+		} else if strings.HasPrefix(line, "exclude ") {
+			mod.Excludes = append(mod.Excludes, parseExclude(strings.TrimPrefix(line, "exclude ")))
+		} else if line == "retract (" {
+			block = "retract"
+		} else if strings.HasPrefix(line, "retract ") {
+			if r, ok := parseRetract(strings.TrimPrefix(line, "retract ")); ok {
+				mod.Retracts = append(mod.Retracts, r)
+			}
+		}
+		comments = comments[:0]
+	}
+	return mod
+}
+
+// parseExclude parses the body of an exclude directive into the excluded
+// module's name, as "path version".
+func parseExclude(spec string) string {
+	return strings.Join(strings.Fields(unquoteRequire(strings.Split(spec, "//")[0])), " ")
+}
+
+// unquoteRequire removes the quotes go.mod allows around a required module
+// path, as in require "gopkg.in/check.v1" v1.0.0.
+func unquoteRequire(require string) string {
+	if !strings.HasPrefix(require, "\"") {
+		return require
+	}
+	end := strings.Index(require[1:], "\"")
+	if end < 0 {
+		return require
+	}
+	return require[1:end+1] + require[end+2:]
+}
+
+// deprecationMessage returns the message of the "Deprecated:" paragraph in
+// the comments attached to a module directive, or an empty string if the
+// module isn't deprecated.
+func deprecationMessage(comments []string) string {
+	for i, comment := range comments {
+		if !strings.HasPrefix(comment, "Deprecated:") {
+			continue
+		}
+		message := []string{strings.TrimSpace(strings.TrimPrefix(comment, "Deprecated:"))}
+		for _, next := range comments[i+1:] {
+			if next == "" {
+				break
+			}
+			message = append(message, next)
+		}
+		return strings.Join(message, " ")
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+package deptree
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeprecationMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments []string
+		want     string
+	}{
+		{name: "no comments", comments: nil, want: ""},
+		{name: "not deprecated", comments: []string{"Package a does things."}, want: ""},
+		{name: "one line", comments: []string{"Deprecated: use b."}, want: "use b."},
+		{
+			name:     "runs to the end of the paragraph",
+			comments: []string{"Package a does things.", "", "Deprecated: use b,", "which is faster.", "", "Other notes."},
+			want:     "use b, which is faster.",
+		},
+		{name: "case matters", comments: []string{"deprecated: use b."}, want: ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := deprecationMessage(test.comments); got != test.want {
+				t.Errorf("deprecationMessage(%q) = %q, want %q", test.comments, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseGoModDirectives(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want ModuleMetadata
+	}{
+		{name: "none", file: "module example.com/a\n", want: ModuleMetadata{}},
+		{name: "go", file: "module example.com/a\n\ngo 1.21\n", want: ModuleMetadata{Go: "1.21"}},
+		{
+			name: "go and toolchain",
+			file: "module example.com/a\n\ngo 1.21.0\n\ntoolchain go1.22.1 // pinned\n",
+			want: ModuleMetadata{Go: "1.21.0", Toolchain: "go1.22.1"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "go.mod")
+			if err := ioutil.WriteFile(file, []byte(test.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mod, err := ParseGoMod(file)
+			if err != nil {
+				t.Fatalf("ParseGoMod() error = %v", err)
+			}
+			if got := (ModuleMetadata{Go: mod.Go, Toolchain: mod.Toolchain}); got != test.want {
+				t.Errorf("ParseGoMod() directives = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseGoModBytes(t *testing.T) {
+	tests := []struct {
+		name  string
+		gomod string
+		want  GoMod
+	}{
+		{
+			name: "module and go directives",
+			gomod: `module example.com/app // the app
+
+go 1.17
+
+toolchain go1.21.0
+`,
+			want: GoMod{Name: "example.com/app", Go: "1.17", Toolchain: "go1.21.0", Requires: []Require{}},
+		},
+		{
+			name: "quoted module path",
+			gomod: `module "example.com/app"
+`,
+			want: GoMod{Name: "example.com/app", Requires: []Require{}},
+		},
+		{
+			name: "deprecated module",
+			gomod: `// Deprecated: use example.com/app/v2 instead.
+module example.com/app
+`,
+			want: GoMod{Name: "example.com/app", Deprecated: "use example.com/app/v2 instead.", Requires: []Require{}},
+		},
+		{
+			name: "require block",
+			gomod: `module example.com/app
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.2.3 // indirect
+
+)
+`,
+			want: GoMod{
+				Name:     "example.com/app",
+				Requires: []Require{{Path: "example.com/a", Version: "v1.0.0"}, {Path: "example.com/b", Version: "v1.2.3", Indirect: true}},
+			},
+		},
+		{
+			name: "single line require",
+			gomod: `module example.com/app
+
+require example.com/a v1.0.0
+require example.com/b v1.2.3 // indirect
+`,
+			want: GoMod{
+				Name:     "example.com/app",
+				Requires: []Require{{Path: "example.com/a", Version: "v1.0.0"}, {Path: "example.com/b", Version: "v1.2.3", Indirect: true}},
+			},
+		},
+		{
+			name: "replace block and single line",
+			gomod: `module example.com/app
+
+replace (
+	example.com/a => ../a
+	example.com/b v1.0.0 => example.com/fork v1.0.1
+)
+
+replace example.com/c => /abs/c // local
+`,
+			want: GoMod{
+				Name:     "example.com/app",
+				Requires: []Require{},
+				Replaces: []ReplaceDirective{
+					{Old: "example.com/a", New: "../a"},
+					{Old: "example.com/b", OldVersion: "v1.0.0", New: "example.com/fork", NewVersion: "v1.0.1"},
+					{Old: "example.com/c", New: "/abs/c"},
+				},
+			},
+		},
+		{
+			name: "exclude block and single line",
+			gomod: `module example.com/app
+
+exclude (
+	example.com/a v1.0.0
+	example.com/a v1.0.1 // broken
+)
+
+exclude example.com/b v2.0.0+incompatible
+`,
+			want: GoMod{
+				Name:     "example.com/app",
+				Requires: []Require{},
+				Excludes: []string{"example.com/a v1.0.0", "example.com/a v1.0.1", "example.com/b v2.0.0+incompatible"},
+			},
+		},
+		{
+			name: "retract block and single line",
+			gomod: `module example.com/app
+
+retract (
+	v1.0.0 // published by accident
+	[v1.1.0, v1.1.5]
+)
+
+retract v2.0.0
+`,
+			want: GoMod{
+				Name:     "example.com/app",
+				Requires: []Require{},
+				Retracts: []Retraction{
+					{Low: "v1.0.0", High: "v1.0.0"},
+					{Low: "v1.1.0", High: "v1.1.5"},
+					{Low: "v2.0.0", High: "v2.0.0"},
+				},
+			},
+		},
+		{
+			name: "go directive after a block",
+			gomod: `module example.com/app
+
+require (
+	example.com/a v1.0.0
+)
+go 1.16
+`,
+			want: GoMod{
+				Name:     "example.com/app",
+				Go:       "1.16",
+				Requires: []Require{{Path: "example.com/a", Version: "v1.0.0"}},
+			},
+		},
+		{
+			name:  "empty file",
+			gomod: "",
+			want:  GoMod{Requires: []Require{}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parseGoModBytes([]byte(test.gomod))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("parseGoModBytes() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestRetractionContains(t *testing.T) {
+	r := Retraction{Low: "v1.1.0", High: "v1.1.5"}
+	for version, want := range map[string]bool{
+		"v1.0.9":      false,
+		"v1.1.0":      true,
+		"v1.1.3":      true,
+		"v1.1.5":      true,
+		"v1.1.6":      false,
+		"v1.1.5-rc.1": true,
+		"v1.1.0-rc.1": false,
+		"v2.0.0":      false,
+	} {
+		if got := r.Contains(version); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", version, got, want)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package deptree
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"time"
+)
+
+// moduleInfo is the contents of a .info file in the module download cache.
+type moduleInfo struct {
+	Version string
+	Time    time.Time
+}
+
+// readModuleInfo reads the .info file the go command wrote to the download
+// cache when it fetched the named module, returning false if there isn't one.
+func (o Options) readModuleInfo(name string) (moduleInfo, bool) {
+	modPath, version := SplitModuleName(name)
+	if version == "" {
+		return moduleInfo{}, false
+	}
+	escaped, escapedVersion := NameAndVersion(EscapeCapitals(modPath + " " + version))
+	infoPath := path.Join(o.ModCache, "cache", "download", escaped, "@v", escapedVersion+".info")
+	fileBytes, err := ioutil.ReadFile(infoPath)
+	if err != nil {
+		return moduleInfo{}, false
+	}
+
+	var info moduleInfo
+	if err := json.Unmarshal(fileBytes, &info); err != nil || info.Time.IsZero() {
+		return moduleInfo{}, false
+	}
+	return info, true
+}
@@ -0,0 +1,324 @@
+package deptree
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+
+	"golang.org/x/mod/module"
+)
+
+// Options configure how a module graph is built and where its modules are
+// resolved from.
+type Options struct {
+	// MaxDepth is the maximum depth to walk, -1 for no limit.
+	MaxDepth int
+	// Gopath is the GOPATH whose src and pkg/mod directories are searched for
+	// modules. It may be a list of directories, as GOPATH can be.
+	Gopath string
+	// ModCache is the module cache, defaulting to GOMODCACHE as it does for
+	// the go command, or else to pkg/mod in the first GOPATH entry, or else
+	// to what go env reports.
+	ModCache string
+	// NoGoEnv never runs go env to find the module cache.
+	NoGoEnv bool
+	// ExactVersions only resolves modules from the cache directory of the
+	// exact version they're required at.
+	ExactVersions bool
+	// PublishTimes reads each module's publish time from the download cache.
+	PublishTimes bool
+	// Trace logs every resolution decision with the standard logger.
+	Trace bool
+	// Jobs is the most go.mod files read at once, 1 to read them one by one.
+	Jobs int
+	// CoalesceVersions walks every module at the version the root module
+	// requires it at rather than the version its parent asks for.
+	CoalesceVersions bool
+	// Proxy is the list of module proxies, in the format of GOPROXY, that
+	// go.mod files missing from GOPATH are fetched from. Empty for none.
+	Proxy string
+	// NoProxy is the comma separated list of module path patterns, in the
+	// format of GONOPROXY, never fetched from Proxy.
+	NoProxy string
+	// ProxyCache is the directory go.mod files fetched from Proxy are kept
+	// in, defaulting to go-mod-dependency-tree in the user's cache directory.
+	ProxyCache string
+	// Vendor reads the graph from the root module's vendor/modules.txt
+	// rather than the module cache.
+	Vendor bool
+	// IncludePaths is the comma separated list of module path patterns, in
+	// the format of GOPRIVATE, the walk is limited to. Empty for every path.
+	IncludePaths string
+	// ExcludePaths is the comma separated list of module path patterns, in
+	// the format of GOPRIVATE, the walk leaves out.
+	ExcludePaths string
+	// NoReplaces walks every module at the version it's required at,
+	// ignoring the root module's replace directives. Those of a workspace
+	// still apply to the modules it uses, as they're read from nowhere else.
+	NoReplaces bool
+	// PruneIndirect doesn't walk the requirements of modules required
+	// // indirect, unless they're also required directly.
+	PruneIndirect bool
+	// ParseCache is the file the parsed go.mod files of the module cache are
+	// kept in between runs. Empty to parse them every time.
+	ParseCache string
+	// Visit, if set, is called as the walk reads the go.mod of each module,
+	// with the module's name and the requirements about to be walked. It's
+	// called from the walk alone, never concurrently, and may be called again
+	// for a module walked deeper.
+	Visit func(name string, requires []Require)
+	// Context stops the walk, and any download from the module proxy, once
+	// it's cancelled. Defaults to context.Background().
+	Context context.Context
+
+	// mods holds the go.mod files outside the module cache parsed so far,
+	// such as the root module's and those of modules replaced with a
+	// directory, which can change between builds so are only kept for one.
+	mods *goModCache
+}
+
+// Option configures Options.
+type Option func(*Options)
+
+// WithMaxDepth limits how deep the graph is walked, -1 for no limit.
+func WithMaxDepth(depth int) Option {
+	return func(o *Options) {
+		o.MaxDepth = depth
+	}
+}
+
+// WithGopath sets the GOPATH modules are resolved from.
+func WithGopath(gopath string) Option {
+	return func(o *Options) {
+		o.Gopath = gopath
+	}
+}
+
+// WithModCache sets the module cache modules are resolved from.
+func WithModCache(dir string) Option {
+	return func(o *Options) {
+		o.ModCache = dir
+	}
+}
+
+// WithNoGoEnv never runs go env to find the module cache when neither it
+// nor GOPATH is given.
+func WithNoGoEnv(noGoEnv bool) Option {
+	return func(o *Options) {
+		o.NoGoEnv = noGoEnv
+	}
+}
+
+// WithExactVersions only resolves modules at the exact version they're
+// required at, treating any other version as unknown.
+func WithExactVersions(exact bool) Option {
+	return func(o *Options) {
+		o.ExactVersions = exact
+	}
+}
+
+// WithPublishTimes reads each module's publish time from the .info files in
+// the module download cache.
+func WithPublishTimes(read bool) Option {
+	return func(o *Options) {
+		o.PublishTimes = read
+	}
+}
+
+// WithTrace logs every resolution decision to stderr.
+func WithTrace(trace bool) Option {
+	return func(o *Options) {
+		o.Trace = trace
+	}
+}
+
+// WithJobs sets the most go.mod files read at once.
+func WithJobs(jobs int) Option {
+	return func(o *Options) {
+		o.Jobs = jobs
+	}
+}
+
+// WithCoalesceVersions walks every module the root module requires at the
+// root's version, wherever in the graph it's required.
+func WithCoalesceVersions(coalesce bool) Option {
+	return func(o *Options) {
+		o.CoalesceVersions = coalesce
+	}
+}
+
+// WithProxy fetches go.mod files missing from GOPATH from the module
+// proxies listed, in the format of GOPROXY. Empty fetches nothing.
+func WithProxy(proxy string) Option {
+	return func(o *Options) {
+		o.Proxy = proxy
+	}
+}
+
+// WithNoProxy never fetches the modules matching patterns, in the format of
+// GONOPROXY, from the module proxy.
+func WithNoProxy(patterns string) Option {
+	return func(o *Options) {
+		o.NoProxy = patterns
+	}
+}
+
+// WithProxyCache sets the directory go.mod files fetched from the module
+// proxy are kept in.
+func WithProxyCache(dir string) Option {
+	return func(o *Options) {
+		o.ProxyCache = dir
+	}
+}
+
+// WithVendor reads the graph from the root module's vendor/modules.txt.
+func WithVendor(vendor bool) Option {
+	return func(o *Options) {
+		o.Vendor = vendor
+	}
+}
+
+// WithIncludePaths limits the walk to the modules whose path matches
+// patterns, in the format of GOPRIVATE.
+func WithIncludePaths(patterns string) Option {
+	return func(o *Options) {
+		o.IncludePaths = patterns
+	}
+}
+
+// WithExcludePaths leaves the modules whose path matches patterns, in the
+// format of GOPRIVATE, out of the walk.
+func WithExcludePaths(patterns string) Option {
+	return func(o *Options) {
+		o.ExcludePaths = patterns
+	}
+}
+
+// WithIgnore leaves the modules whose path matches patterns, in the format
+// of GOPRIVATE, out of the walk, like WithExcludePaths but adding to any
+// patterns already given.
+func WithIgnore(patterns string) Option {
+	return func(o *Options) {
+		if o.ExcludePaths != "" && patterns != "" {
+			o.ExcludePaths += ","
+		}
+		o.ExcludePaths += patterns
+	}
+}
+
+// WithReplaceHandling sets whether the root module's replace directives are
+// followed, which they are by default. Without them every module is walked
+// at the version it's required at.
+func WithReplaceHandling(handle bool) Option {
+	return func(o *Options) {
+		o.NoReplaces = !handle
+	}
+}
+
+// WithPruneIndirect doesn't walk the requirements of modules only required
+// // indirect.
+func WithPruneIndirect(prune bool) Option {
+	return func(o *Options) {
+		o.PruneIndirect = prune
+	}
+}
+
+// WithParseCache keeps the parsed go.mod files of the module cache in file
+// between runs.
+func WithParseCache(file string) Option {
+	return func(o *Options) {
+		o.ParseCache = file
+	}
+}
+
+// WithVisit calls visit with each module and its requirements as the walk
+// reads them, so progress can be reported while the graph is built.
+func WithVisit(visit func(name string, requires []Require)) Option {
+	return func(o *Options) {
+		o.Visit = visit
+	}
+}
+
+// WithOptions starts from o, such as the options of another graph, so a graph
+// derived from it resolves modules the same way without working out the
+// defaults again. Options given after it override o's.
+func WithOptions(o Options) Option {
+	return func(opts *Options) {
+		*opts = o
+	}
+}
+
+// WithContext stops building the graph once ctx is cancelled.
+func WithContext(ctx context.Context) Option {
+	return func(o *Options) {
+		o.Context = ctx
+	}
+}
+
+// follows reports whether the walk takes in the module with the given path,
+// as filtered by IncludePaths and ExcludePaths.
+func (o Options) follows(modPath string) bool {
+	if o.IncludePaths != "" && !module.MatchPrefixPatterns(o.IncludePaths, modPath) {
+		return false
+	}
+	return !module.MatchPrefixPatterns(o.ExcludePaths, modPath)
+}
+
+// gopaths returns each directory in the GOPATH list, skipping empty entries
+// so nothing is looked for relative to the working directory.
+func (o Options) gopaths() []string {
+	gopaths := make([]string, 0)
+	for _, gopath := range filepath.SplitList(o.Gopath) {
+		if gopath != "" {
+			gopaths = append(gopaths, gopath)
+		}
+	}
+	return gopaths
+}
+
+// defaultModCache returns the module cache the go command would use:
+// GOMODCACHE, or else pkg/mod in the first GOPATH entry, or else what go env
+// reports. It's empty if none of them says.
+func (o Options) defaultModCache() string {
+	if modCache := os.Getenv("GOMODCACHE"); modCache != "" {
+		return modCache
+	}
+	if gopaths := o.gopaths(); len(gopaths) > 0 {
+		return path.Join(gopaths[0], "pkg", "mod")
+	}
+	if o.NoGoEnv {
+		return ""
+	}
+	_, modCache, err := GoEnv()
+	if err != nil {
+		return ""
+	}
+	return modCache
+}
+
+// newOptions applies opts over the defaults.
+func newOptions(opts ...Option) Options {
+	o := Options{
+		MaxDepth: -1,
+		Jobs:     1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+	if o.ModCache == "" {
+		o.ModCache = o.defaultModCache()
+	}
+	o.mods = newGoModCache()
+	if o.ProxyCache == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			cache = os.TempDir()
+		}
+		o.ProxyCache = path.Join(cache, "go-mod-dependency-tree")
+	}
+	return o
+}
@@ -0,0 +1,157 @@
+package deptree
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// parseCacheVersion is bumped whenever parsing changes, so go.mod files
+// parsed by an older version are parsed again.
+const parseCacheVersion = 3
+
+// parseCache is the parsed go.mod of every module cache file read by earlier
+// runs, persisted to a file between them. Files in the module cache never
+// change, so they never need parsing twice.
+type parseCache struct {
+	mu    sync.Mutex
+	file  string
+	mods  map[string]GoMod
+	dirty bool
+}
+
+// parseCacheFile is the on disk form of a parseCache.
+type parseCacheFile struct {
+	Version int              `json:"version"`
+	Mods    map[string]GoMod `json:"mods"`
+}
+
+// parseCaches holds the parse cache loaded from each file, so every graph
+// built in the process shares it.
+var (
+	parseCaches   = make(map[string]*parseCache)
+	parseCachesMu sync.Mutex
+)
+
+// loadParseCache returns the parse cache persisted to file, loading it the
+// first time it's asked for. A missing, unreadable or outdated file gives an
+// empty cache.
+func loadParseCache(file string) *parseCache {
+	parseCachesMu.Lock()
+	defer parseCachesMu.Unlock()
+	if c, ok := parseCaches[file]; ok {
+		return c
+	}
+	c := &parseCache{file: file, mods: make(map[string]GoMod)}
+	if fileBytes, err := ioutil.ReadFile(file); err == nil {
+		var stored parseCacheFile
+		if json.Unmarshal(fileBytes, &stored) == nil && stored.Version == parseCacheVersion && stored.Mods != nil {
+			c.mods = stored.Mods
+		}
+	}
+	parseCaches[file] = c
+	return c
+}
+
+// parse returns the parsed go.mod at modFile, parsing it and adding it to
+// the cache if it isn't there yet.
+func (c *parseCache) parse(modFile string) (GoMod, error) {
+	c.mu.Lock()
+	mod, ok := c.mods[modFile]
+	c.mu.Unlock()
+	if ok {
+		return mod, nil
+	}
+	mod, err := ParseGoMod(modFile)
+	if err != nil {
+		return GoMod{}, err
+	}
+	c.mu.Lock()
+	c.mods[modFile] = mod
+	c.dirty = true
+	c.mu.Unlock()
+	return mod, nil
+}
+
+// save writes the cache back to its file if anything was added to it. The
+// cache only saves parsing files again, so failing to write it is ignored.
+func (c *parseCache) save() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return
+	}
+	fileBytes, err := json.Marshal(parseCacheFile{Version: parseCacheVersion, Mods: c.mods})
+	if err != nil {
+		return
+	}
+	if writeFileAtomic(c.file, fileBytes) == nil {
+		c.dirty = false
+	}
+}
+
+// writeFileAtomic writes data to file, creating its directory if need be.
+// It writes to a temporary file first and renames it into place, so neither
+// a concurrent run nor one after an interrupted write ever reads half a
+// file.
+func writeFileAtomic(file string, data []byte) error {
+	if err := os.MkdirAll(path.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(path.Dir(file), path.Base(file)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// TempFile creates files only the user can read.
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// immutable reports whether the go.mod in dir is in the module cache, whose
+// files never change once written.
+func (o Options) immutable(dir string) bool {
+	caches := []string{o.ModCache}
+	for _, gopath := range o.gopaths() {
+		caches = append(caches, path.Join(gopath, "pkg", "mod"))
+	}
+	for _, cache := range caches {
+		if cache != "" && strings.HasPrefix(dir, cache+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseModuleGoMod parses the go.mod in dir, found by resolving a module.
+// If dir is in the module cache, its go.mod is kept for every later graph,
+// going through the persistent parse cache if there is one.
+func (o Options) parseModuleGoMod(dir string) (GoMod, error) {
+	if !o.immutable(dir) {
+		return o.parseGoModFile(dir)
+	}
+	file := path.Join(dir, "go.mod")
+	if o.ParseCache == "" {
+		return immutableGoMods.load(file, func() (GoMod, error) {
+			return ParseGoMod(file)
+		})
+	}
+	c := loadParseCache(o.ParseCache)
+	return immutableGoMods.load(file, func() (GoMod, error) {
+		return c.parse(file)
+	})
+}
@@ -0,0 +1,104 @@
+package deptree
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeFile writes content to name, creating its directory.
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeParseCache writes a parse cache file of the given version holding mods.
+func writeParseCache(t *testing.T, file string, version int, mods map[string]GoMod) {
+	t.Helper()
+	fileBytes, err := json.Marshal(parseCacheFile{Version: version, Mods: mods})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, file, string(fileBytes))
+}
+
+func TestParseCacheSave(t *testing.T) {
+	dir := t.TempDir()
+	modFile := filepath.Join(dir, "example.com", "x@v1.0.0", "go.mod")
+	writeFile(t, modFile, "module example.com/x\n\nrequire example.com/y v1.0.0\n")
+	file := filepath.Join(dir, "cache", "parsed.json")
+
+	c := loadParseCache(file)
+	mod, err := c.parse(modFile)
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	c.save()
+
+	fileBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatalf("cache wasn't saved: %v", err)
+	}
+	var stored parseCacheFile
+	if err := json.Unmarshal(fileBytes, &stored); err != nil {
+		t.Fatalf("cache isn't JSON: %v", err)
+	}
+	want := parseCacheFile{Version: parseCacheVersion, Mods: map[string]GoMod{modFile: mod}}
+	if !reflect.DeepEqual(stored, want) {
+		t.Errorf("saved cache = %+v, want %+v", stored, want)
+	}
+}
+
+func TestParseModuleGoMod(t *testing.T) {
+	dir := t.TempDir()
+	modCache := filepath.Join(dir, "pkg", "mod")
+	cached := filepath.Join(modCache, "example.com", "x@v1.0.0")
+	writeFile(t, filepath.Join(cached, "go.mod"), "module example.com/x\n")
+	outside := filepath.Join(dir, "src", "example.com", "x")
+	writeFile(t, filepath.Join(outside, "go.mod"), "module example.com/x\n")
+
+	// The cached entries differ from the files, to tell which was read.
+	fromCache := GoMod{Name: "example.com/x", Requires: []Require{{Path: "example.com/cached", Version: "v1.0.0"}}}
+	tests := []struct {
+		name    string
+		version int
+		dir     string
+		want    []Require
+	}{
+		{name: "module cache", version: parseCacheVersion, dir: cached, want: fromCache.Requires},
+		{name: "outdated cache", version: parseCacheVersion - 1, dir: cached, want: []Require{}},
+		{name: "outside the module cache", version: parseCacheVersion, dir: outside, want: []Require{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "parsed.json")
+			writeParseCache(t, file, test.version, map[string]GoMod{
+				filepath.Join(cached, "go.mod"):  fromCache,
+				filepath.Join(outside, "go.mod"): fromCache,
+			})
+			o := newOptions(WithGopath(dir), WithModCache(modCache), WithParseCache(file))
+			// Each case reads the same files, so forget what the last one
+			// read.
+			immutableGoMods.mu.Lock()
+			delete(immutableGoMods.files, filepath.Join(cached, "go.mod"))
+			delete(immutableGoMods.files, filepath.Join(outside, "go.mod"))
+			immutableGoMods.mu.Unlock()
+
+			mod, err := o.parseModuleGoMod(test.dir)
+			if err != nil {
+				t.Fatalf("parseModuleGoMod() error = %v", err)
+			}
+			if !reflect.DeepEqual(mod.Requires, test.want) {
+				t.Errorf("requires = %v, want %v", mod.Requires, test.want)
+			}
+		})
+	}
+}
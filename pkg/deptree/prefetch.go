@@ -0,0 +1,86 @@
+package deptree
+
+import (
+	"log"
+	"sync"
+)
+
+// prefetcher reads the go.mod of modules the walk is about to reach in the
+// background, with at most limit goroutines doing so at once. Requests queue
+// up until a goroutine is free, and the goroutines exit once the queue is
+// empty, so none are left behind once the walk is done and wait returns.
+type prefetcher struct {
+	g *Graph
+	// opts are the graph's options without tracing, as trace output from the
+	// background would interleave with the walk's.
+	opts Options
+
+	mu      sync.Mutex
+	queue   []string
+	workers int
+	limit   int
+	wg      sync.WaitGroup
+}
+
+func newPrefetcher(g *Graph, jobs int) *prefetcher {
+	quiet := g.opts
+	quiet.Trace = false
+	return &prefetcher{g: g, opts: quiet, limit: jobs}
+}
+
+// add queues the go.mod of each of the named modules to be read, starting
+// goroutines to read them up to the limit.
+func (p *prefetcher) add(names []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.queue = append(p.queue, names...)
+	for p.workers < p.limit && p.workers < len(p.queue) {
+		p.workers++
+		p.wg.Add(1)
+		go p.work()
+	}
+}
+
+// work reads queued go.mod files until there are none left, or the walk is
+// cancelled.
+func (p *prefetcher) work() {
+	defer p.wg.Done()
+	for {
+		p.mu.Lock()
+		if len(p.queue) == 0 || p.workers > p.limit || p.opts.Context.Err() != nil {
+			p.workers--
+			p.mu.Unlock()
+			return
+		}
+		name := p.queue[0]
+		p.queue = p.queue[1:]
+		p.mu.Unlock()
+
+		if _, _, err := p.g.readRequirement(name, name, p.opts); tooManyFiles(err) {
+			p.throttle()
+		}
+	}
+}
+
+// throttle reads one fewer go.mod file at once for the rest of the walk,
+// after a read ran out of file descriptors however often it was tried. The
+// walk reads the module again itself, so it doesn't end up unknown. At least
+// one read is always allowed.
+func (p *prefetcher) throttle() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.limit <= 1 {
+		return
+	}
+	p.limit--
+	log.Printf("warning: ran out of file descriptors, reading at most %d go.mod files at once", p.limit)
+}
+
+// wait waits for every goroutine reading in the background to exit.
+// Requests still queued once the walk is over are dropped.
+func (p *prefetcher) wait() {
+	p.mu.Lock()
+	p.queue = nil
+	p.mu.Unlock()
+	p.wg.Wait()
+}
@@ -0,0 +1,179 @@
+package deptree
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// DefaultProxy is the module proxy the go command uses when GOPROXY isn't set.
+const DefaultProxy = "https://proxy.golang.org,direct"
+
+// errNotOnProxy is returned when a proxy doesn't have a module, which lets
+// the next proxy in the list be tried.
+var errNotOnProxy = errors.New("module not found on proxy")
+
+var proxyClient = &http.Client{Timeout: 30 * time.Second}
+
+// proxyGoMod returns the go.mod of the module at the given version from the
+// module proxies, keeping a copy in ProxyCache so later runs don't need the
+// network. It returns errModuleNotFound if no proxy has it, and the error
+// otherwise, so a network failure isn't mistaken for a missing module.
+func (o Options) proxyGoMod(modPath, version string) (GoMod, error) {
+	if o.Proxy == "" || version == "" || module.MatchPrefixPatterns(o.NoProxy, modPath) {
+		return GoMod{}, errModuleNotFound
+	}
+	escapedPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return GoMod{}, errModuleNotFound
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return GoMod{}, errModuleNotFound
+	}
+	file := path.Join(o.ProxyCache, escapedPath, "@v", escapedVersion+".mod")
+
+	return immutableGoMods.load(file, func() (GoMod, error) {
+		if _, err := os.Stat(file); err == nil {
+			return ParseGoMod(file)
+		}
+		body, err := o.download(escapedPath + "/@v/" + escapedVersion + ".mod")
+		if err != nil {
+			if o.Trace {
+				log.Printf("trace:   unable to fetch %s %s from the module proxy: %v", modPath, version, err)
+			}
+			if err == errNotOnProxy {
+				return GoMod{}, errModuleNotFound
+			}
+			return GoMod{}, fmt.Errorf("fetching %s %s from the module proxy: %v", modPath, version, err)
+		}
+		if o.Trace {
+			log.Printf("trace:   fetched %s %s from the module proxy", modPath, version)
+		}
+		// The cache only saves fetching the file again, so failing to write
+		// it isn't worth failing over.
+		writeFileAtomic(file, body)
+		return parseGoModBytes(body), nil
+	})
+}
+
+// download fetches file from each proxy in Proxy in turn, following the
+// rules of GOPROXY: a proxy listed after a comma is only tried if the one
+// before doesn't have the module, one listed after a pipe is tried whatever
+// the error, and the list stops at "direct" or "off" as there's no proxy to
+// ask. Failures that look temporary are tried again before moving on.
+func (o Options) download(file string) ([]byte, error) {
+	err := errNotOnProxy
+	proxies := o.Proxy
+	for proxies != "" {
+		end := strings.IndexAny(proxies, ",|")
+		proxy, separator := proxies, byte(0)
+		if end >= 0 {
+			proxy, separator, proxies = proxies[:end], proxies[end], proxies[end+1:]
+		} else {
+			proxies = ""
+		}
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "direct" || proxy == "off" {
+			break
+		}
+		var body []byte
+		err = withRetry(o.Context, func() error {
+			var fetchErr error
+			body, fetchErr = fetch(o.Context, strings.TrimRight(proxy, "/")+"/"+file)
+			return fetchErr
+		})
+		if err == nil {
+			return body, nil
+		}
+		if err != errNotOnProxy && separator != '|' {
+			break
+		}
+	}
+	return nil, err
+}
+
+// ProxyVersions returns the released versions of modPath the module proxies
+// list, lowest first. A module with no releases is listed at the version the
+// proxies return as its latest, usually a pseudo-version. It returns no
+// versions if the proxies don't have the module, or if there's no proxy to
+// ask about it.
+func (o Options) ProxyVersions(modPath string) ([]string, error) {
+	if o.Proxy == "" || module.MatchPrefixPatterns(o.NoProxy, modPath) {
+		return nil, nil
+	}
+	escapedPath, err := module.EscapePath(modPath)
+	if err != nil {
+		return nil, err
+	}
+	body, err := o.download(escapedPath + "/@v/list")
+	if err == errNotOnProxy {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	versions := make([]string, 0)
+	for _, version := range strings.Fields(string(body)) {
+		if semver.IsValid(version) {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 0 {
+		body, err := o.download(escapedPath + "/@latest")
+		if err == errNotOnProxy {
+			return nil, nil
+		} else if err != nil {
+			return nil, err
+		}
+		var latest struct{ Version string }
+		if err := json.Unmarshal(body, &latest); err != nil {
+			return nil, fmt.Errorf("reading the latest version of %s: %v", modPath, err)
+		}
+		if semver.IsValid(latest.Version) {
+			versions = append(versions, latest.Version)
+		}
+	}
+	sort.Slice(versions, func(a, b int) bool {
+		return semver.Compare(versions[a], versions[b]) < 0
+	})
+	return versions, nil
+}
+
+// ReadGoMod returns the go.mod of the module at the given version, from
+// GOPATH or else the module proxy.
+func (o Options) ReadGoMod(modPath, version string) (GoMod, error) {
+	return o.readGoMod(modPath + " " + version)
+}
+
+// fetch returns the body of url, or errNotOnProxy if the proxy says it
+// doesn't have it. The request is abandoned if ctx is cancelled.
+func fetch(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := proxyClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		return nil, errNotOnProxy
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{url: url, status: resp.Status, code: resp.StatusCode}
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,234 @@
+package deptree
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// proxyServer is a module proxy answering every request with one status,
+// counting the requests it gets.
+type proxyServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests int
+}
+
+func newProxyServer(t *testing.T, status int, body string) *proxyServer {
+	p := &proxyServer{}
+	p.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		p.requests++
+		p.mu.Unlock()
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *proxyServer) count() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests
+}
+
+func TestDownloadGoMod(t *testing.T) {
+	const gomod = "module example.com/a\n"
+	tests := []struct {
+		name string
+		// proxies is the GOPROXY list, with each %d replaced by the URL of
+		// the server at that position in statuses.
+		proxies  string
+		statuses []int
+		want     string
+		wantErr  error
+		// wantRequests is how many requests each server gets.
+		wantRequests []int
+	}{
+		{
+			name:         "first proxy has it",
+			proxies:      "%0,%1",
+			statuses:     []int{http.StatusOK, http.StatusOK},
+			want:         gomod,
+			wantRequests: []int{1, 0},
+		},
+		{
+			name:         "comma falls through on not found",
+			proxies:      "%0,%1",
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			want:         gomod,
+			wantRequests: []int{1, 1},
+		},
+		{
+			name:         "comma falls through on gone",
+			proxies:      "%0,%1",
+			statuses:     []int{http.StatusGone, http.StatusOK},
+			want:         gomod,
+			wantRequests: []int{1, 1},
+		},
+		{
+			name:         "comma stops on other errors",
+			proxies:      "%0,%1",
+			statuses:     []int{http.StatusForbidden, http.StatusOK},
+			wantRequests: []int{1, 0},
+		},
+		{
+			name:         "pipe falls through on any error",
+			proxies:      "%0|%1",
+			statuses:     []int{http.StatusForbidden, http.StatusOK},
+			want:         gomod,
+			wantRequests: []int{1, 1},
+		},
+		{
+			name:         "server errors are tried again",
+			proxies:      "%0|%1",
+			statuses:     []int{http.StatusBadGateway, http.StatusOK},
+			want:         gomod,
+			wantRequests: []int{maxAttempts, 1},
+		},
+		{
+			name:         "nobody has it",
+			proxies:      "%0,%1",
+			statuses:     []int{http.StatusNotFound, http.StatusNotFound},
+			wantErr:      errNotOnProxy,
+			wantRequests: []int{1, 1},
+		},
+		{
+			name:         "direct ends the list",
+			proxies:      "%0,direct,%1",
+			statuses:     []int{http.StatusNotFound, http.StatusOK},
+			wantErr:      errNotOnProxy,
+			wantRequests: []int{1, 0},
+		},
+		{
+			name:         "off ends the list",
+			proxies:      "off,%0",
+			statuses:     []int{http.StatusOK},
+			wantErr:      errNotOnProxy,
+			wantRequests: []int{0},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			servers := make([]*proxyServer, 0, len(test.statuses))
+			proxies := test.proxies
+			for pos, status := range test.statuses {
+				server := newProxyServer(t, status, gomod)
+				servers = append(servers, server)
+				// A trailing slash is allowed, as GOPROXY allows it.
+				proxies = strings.Replace(proxies, "%"+strconv.Itoa(pos), server.URL+"/", 1)
+			}
+			o := newOptions(WithProxy(proxies))
+
+			body, err := o.download("example.com/a/@v/v1.0.0.mod")
+			if test.want != "" {
+				if err != nil {
+					t.Fatalf("download() error = %v", err)
+				}
+				if string(body) != test.want {
+					t.Errorf("download() = %q, want %q", body, test.want)
+				}
+			} else if err == nil {
+				t.Errorf("download() = %q, want an error", body)
+			} else if test.wantErr != nil && err != test.wantErr {
+				t.Errorf("download() error = %v, want %v", err, test.wantErr)
+			}
+			for pos, server := range servers {
+				if got := server.count(); got != test.wantRequests[pos] {
+					t.Errorf("proxy %d got %d requests, want %d", pos, got, test.wantRequests[pos])
+				}
+			}
+		})
+	}
+}
+
+func TestProxyGoModMissingIsUnknown(t *testing.T) {
+	server := newProxyServer(t, http.StatusNotFound, "")
+	o := newOptions(WithProxy(server.URL), WithProxyCache(t.TempDir()))
+	if _, err := o.proxyGoMod("example.com/missing", "v1.0.0"); err != errModuleNotFound {
+		t.Errorf("proxyGoMod() error = %v, want %v", err, errModuleNotFound)
+	}
+}
+
+func TestProxyGoModKeepsACopy(t *testing.T) {
+	server := newProxyServer(t, http.StatusOK, "module example.com/Kept\n")
+	cache := t.TempDir()
+	o := newOptions(WithProxy(server.URL), WithProxyCache(cache))
+	for i := 0; i < 2; i++ {
+		gomod, err := o.proxyGoMod("example.com/Kept", "v1.0.0")
+		if err != nil {
+			t.Fatalf("proxyGoMod() error = %v", err)
+		}
+		if gomod.Name != "example.com/Kept" {
+			t.Errorf("proxyGoMod() name = %q, want %q", gomod.Name, "example.com/Kept")
+		}
+	}
+	if got := server.count(); got != 1 {
+		t.Errorf("proxy got %d requests, want 1", got)
+	}
+	if _, err := os.Stat(filepath.Join(cache, "example.com", "!kept", "@v", "v1.0.0.mod")); err != nil {
+		t.Errorf("fetched go.mod wasn't kept: %v", err)
+	}
+}
+
+// newFileProxy is a module proxy serving files, keyed by their path on the
+// proxy, and answering 404 for anything else.
+func newFileProxy(t *testing.T, files map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProxyVersions(t *testing.T) {
+	server := newFileProxy(t, map[string]string{
+		"/example.com/released/@v/list":   "v1.10.0\nv1.2.0\nnot-a-version\nv1.9.0-rc.1\n",
+		"/example.com/unreleased/@v/list": "",
+		"/example.com/unreleased/@latest": `{"Version": "v0.0.0-20200101000000-abcdefabcdef"}`,
+		"/example.com/!upper/@v/list":     "v1.0.0\n",
+	})
+	tests := []struct {
+		modPath string
+		noProxy string
+		want    []string
+	}{
+		{modPath: "example.com/released", want: []string{"v1.2.0", "v1.9.0-rc.1", "v1.10.0"}},
+		{modPath: "example.com/unreleased", want: []string{"v0.0.0-20200101000000-abcdefabcdef"}},
+		{modPath: "example.com/Upper", want: []string{"v1.0.0"}},
+		{modPath: "example.com/missing"},
+		{modPath: "example.com/released", noProxy: "example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.modPath, func(t *testing.T) {
+			o := newOptions(WithProxy(server.URL), WithNoProxy(test.noProxy))
+			got, err := o.ProxyVersions(test.modPath)
+			if err != nil {
+				t.Fatalf("ProxyVersions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ProxyVersions() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProxyGoModFailingIsAnError(t *testing.T) {
+	server := newProxyServer(t, http.StatusForbidden, "")
+	o := newOptions(WithProxy(server.URL), WithProxyCache(t.TempDir()))
+	if _, err := o.proxyGoMod("example.com/forbidden", "v1.0.0"); err == nil || err == errModuleNotFound {
+		t.Errorf("proxyGoMod() error = %v, want the proxy's error", err)
+	}
+}
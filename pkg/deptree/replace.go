@@ -0,0 +1,47 @@
+package deptree
+
+import (
+	"path"
+	"strings"
+)
+
+// Replacement returns the root module's replace directive for the module
+// named, as stored in Indexes, preferring a directive for its exact version
+// over one for every version as the go command does. There's none if the
+// graph was built with WithReplaceHandling(false).
+func (g *Graph) Replacement(name string) (ReplaceDirective, bool) {
+	modPath, version := SplitModuleName(name)
+	if _, ok := g.workspace[modPath]; g.opts.NoReplaces && !ok {
+		return ReplaceDirective{}, false
+	}
+	var found ReplaceDirective
+	ok := false
+	for _, r := range g.Replaces {
+		if r.Old != modPath {
+			continue
+		}
+		if r.OldVersion == version {
+			return r, true
+		}
+		if r.OldVersion == "" {
+			found, ok = r, true
+		}
+	}
+	return found, ok
+}
+
+// IsLocal reports whether the replacement is a directory rather than another
+// module.
+func (r ReplaceDirective) IsLocal() bool {
+	return r.NewVersion == "" && (r.New == "." || r.New == ".." || path.IsAbs(r.New) ||
+		strings.HasPrefix(r.New, "./") || strings.HasPrefix(r.New, "../"))
+}
+
+// LocalDir returns the directory a local replacement points at, relative
+// paths being relative to dir, the directory of the go.mod declaring it.
+func (r ReplaceDirective) LocalDir(dir string) string {
+	if path.IsAbs(r.New) {
+		return r.New
+	}
+	return path.Join(dir, r.New)
+}
@@ -0,0 +1,245 @@
+package deptree
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// NameAndVersion splits a module name written as either "path@version" or
+// "path version" into its path and version.
+func NameAndVersion(module string) (string, string) {
+	if strings.Contains(module, "@") {
+		s := strings.Split(module, "@")
+		return s[0], s[1]
+	}
+	s := strings.Split(module, " ")
+	if len(s) == 1 {
+		return s[0], ""
+	}
+	return s[0], s[1]
+}
+
+func (o Options) constructFilePath(dep string) (string, bool) {
+	if candidate, ok := o.findFilePath(o.ResolutionCandidates(dep)); ok {
+		return candidate, true
+	}
+
+	if o.Trace {
+		log.Printf("trace: no candidate path found for %s", dep)
+	}
+	return "", false
+}
+
+// ResolutionCandidates returns every directory constructFilePath tries for
+// dep, a module name with its capitals escaped, in the order it tries them.
+func (o Options) ResolutionCandidates(dep string) []string {
+	module, version := NameAndVersion(dep)
+	// Some tools write module paths with a trailing separator.
+	module = strings.TrimRight(module, "/")
+	if o.Trace && version != "" && !semver.IsValid(version) {
+		log.Printf("trace:   %s isn't a valid semantic version, no module cache directory will match it", version)
+	}
+
+	candidates := o.candidateFilePaths(module, version)
+	// Caches written by older tools don't always escape capitals, so as a last
+	// resort try the module path exactly as it was written.
+	if unescaped := UnescapeCapitals(module); unescaped != module {
+		candidates = append(candidates, o.candidateFilePaths(unescaped, version)...)
+	}
+
+	// Versions without a suffix and paths without capitals give the same
+	// directory more than once, which only needs trying the first time.
+	unique := candidates[:0]
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if !seen[candidate] {
+			seen[candidate] = true
+			unique = append(unique, candidate)
+		}
+	}
+	return unique
+}
+
+// candidateFilePaths returns the directories a module could live in, in the
+// order they should be tried.
+func (o Options) candidateFilePaths(module, version string) []string {
+	// The module cache names directories after the full version, including
+	// any pre-release, pseudo-version or +incompatible suffix. The module
+	// cache is tried before each GOPATH entry's own pkg/mod, in case the
+	// cache was moved after they were filled.
+	gopaths := o.gopaths()
+	cached := make([]string, 0, len(gopaths)+1)
+	if o.ModCache != "" {
+		cached = append(cached, path.Join(o.ModCache, module+"@"+version))
+	}
+	for _, gopath := range gopaths {
+		cached = append(cached, path.Join(gopath, "pkg", "mod", module+"@"+version))
+	}
+	if o.ExactVersions && version != "" {
+		return cached
+	}
+
+	// GOPATH/src holds module paths as written, only the module cache escapes
+	// capitals, so vanity paths like gopkg.in/Foo.v2 differ between the two.
+	candidates := make([]string, 0, len(gopaths)+len(cached))
+	for _, gopath := range gopaths {
+		candidates = append(candidates, path.Join(gopath, "src", UnescapeCapitals(module)))
+	}
+	return append(candidates, cached...)
+}
+
+// findFilePath returns the first of the candidates that exists.
+func (o Options) findFilePath(candidates []string) (string, bool) {
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil || !os.IsNotExist(err) {
+			if o.Trace {
+				log.Printf("trace:   tried %s: found", candidate)
+			}
+			return candidate, true
+		}
+		if o.Trace {
+			log.Printf("trace:   tried %s: not found", candidate)
+		}
+	}
+	return "", false
+}
+
+// FindParentModule returns the nearest parent directory of dir containing a
+// go.mod, stopping at the filesystem root.
+func FindParentModule(dir string) (string, bool) {
+	for parent := path.Dir(dir); parent != dir; dir, parent = parent, path.Dir(parent) {
+		if _, err := os.Stat(path.Join(parent, "go.mod")); err == nil {
+			return parent, true
+		}
+	}
+	return "", false
+}
+
+// GoEnv asks the go command for the GOPATH and GOMODCACHE it would use.
+func GoEnv() (string, string, error) {
+	out, err := exec.Command("go", "env", "GOPATH", "GOMODCACHE").Output()
+	if err != nil {
+		return "", "", err
+	}
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) < 2 {
+		return strings.TrimSpace(lines[0]), "", nil
+	}
+	return strings.TrimSpace(lines[0]), strings.TrimSpace(lines[1]), nil
+}
+
+// ModuleName returns the name of the module whose go.mod is in dir. A module
+// nested in a directory below the one its module path points at is named
+// after its directory.
+func ModuleName(dir string) (string, error) {
+	fileBytes, err := ioutil.ReadFile(path.Join(dir, "go.mod"))
+	if err != nil {
+		return "", fmt.Errorf("reading go.mod: %v", err)
+	}
+
+	lines := strings.Split(string(fileBytes), "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "module ") {
+			modAddress := strings.Split(line, "module ")[1]
+			if strings.Contains(modAddress, "\"") {
+				modAddress = modAddress[1 : len(modAddress)-1]
+			}
+			if strings.HasSuffix(dir, modAddress) || !strings.Contains(dir, modAddress) {
+				return modAddress, nil
+			}
+			return modAddress + strings.Split(dir, modAddress)[1], nil
+		}
+	}
+	return "", errors.New("invalid go.mod, no module name")
+}
+
+// EscapeCapitals escapes a module name, its path optionally followed by a
+// version, as the module cache names its directories, each capital letter
+// becoming an exclamation mark followed by the lower case letter. The path and
+// version are escaped by golang.org/x/mod/module, so the result matches the go
+// command exactly, but names it rejects, such as the paths in test fixtures,
+// are escaped letter by letter instead.
+func EscapeCapitals(name string) string {
+	modPath, version := NameAndVersion(name)
+	escaped, err := module.EscapePath(modPath)
+	if err != nil {
+		escaped = escapeLetters(modPath)
+	}
+	if version == "" {
+		return escaped
+	}
+	escapedVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		escapedVersion = escapeLetters(version)
+	}
+	return escaped + " " + escapedVersion
+}
+
+// escapeLetters escapes each capital letter in s as EscapeCapitals does.
+func escapeLetters(s string) string {
+	letters := strings.Split(s, "")
+	newName := ""
+	for _, letter := range letters {
+		if strings.ToLower(letter) != letter {
+			newName += "!" + strings.ToLower(letter)
+		} else {
+			newName += letter
+		}
+	}
+	return newName
+}
+
+// UnescapeCapitals undoes EscapeCapitals for a module path.
+func UnescapeCapitals(name string) string {
+	if unescaped, err := module.UnescapePath(name); err == nil {
+		return unescaped
+	}
+	letters := strings.Split(name, "")
+	newName := ""
+	for i := 0; i < len(letters); i++ {
+		if letters[i] == "!" && i+1 < len(letters) {
+			i++
+			newName += strings.ToUpper(letters[i])
+		} else {
+			newName += letters[i]
+		}
+	}
+	return newName
+}
+
+// ModuleDir returns the directory holding the source of the module at index
+// i, following any replacement the root module makes for it. It returns false
+// if the source isn't on disk, such as for a module whose go.mod was fetched
+// from the module proxy.
+func (g *Graph) ModuleDir(i int) (string, bool) {
+	if i == 0 {
+		return g.Dir, g.Dir != ""
+	}
+	name := g.Indexes[i]
+	if g.opts.Vendor {
+		modPath, _ := SplitModuleName(name)
+		dir := path.Join(g.Dir, "vendor", modPath)
+		_, err := os.Stat(dir)
+		return dir, err == nil
+	}
+	if r, ok := g.Replacement(name); ok {
+		if r.IsLocal() {
+			return r.LocalDir(g.Dir), true
+		}
+		name = r.New + " " + r.NewVersion
+	}
+	// Only the walk traces.
+	quiet := g.opts
+	quiet.Trace = false
+	return quiet.findFilePath(quiet.ResolutionCandidates(EscapeCapitals(name)))
+}
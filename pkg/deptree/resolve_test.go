@@ -0,0 +1,215 @@
+package deptree
+
+import (
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConstructFilePath(t *testing.T) {
+	gopath := fixtureGopath(t)
+	o := newOptions(WithGopath(gopath))
+
+	tests := []struct {
+		name   string
+		dep    string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "module cache",
+			dep:    "example.com/c v1.0.0",
+			want:   "pkg/mod/example.com/c@v1.0.0",
+			wantOK: true,
+		},
+		{
+			name:   "trailing separator",
+			dep:    "example.com/c/ v1.0.0",
+			want:   "pkg/mod/example.com/c@v1.0.0",
+			wantOK: true,
+		},
+		{
+			name:   "GOPATH/src before the module cache",
+			dep:    "example.com/both v1.0.0",
+			want:   "src/example.com/both",
+			wantOK: true,
+		},
+		{
+			name:   "capitals left unescaped in the cache",
+			dep:    "example.com/!irregular v1.0.0",
+			want:   "pkg/mod/example.com/Irregular@v1.0.0",
+			wantOK: true,
+		},
+		{
+			name:   "dotted version suffix",
+			dep:    "gopkg.in/yaml.v3 v3.0.1",
+			want:   "pkg/mod/gopkg.in/yaml.v3@v3.0.1",
+			wantOK: true,
+		},
+		{
+			name:   "capitals escaped before the version suffix",
+			dep:    "gopkg.in/!foo.v2 v2.1.0",
+			want:   "pkg/mod/gopkg.in/!foo.v2@v2.1.0",
+			wantOK: true,
+		},
+		{
+			name:   "capitals unescaped in GOPATH/src",
+			dep:    "gopkg.in/!bar.v1 v1.0.0",
+			want:   "src/gopkg.in/Bar.v1",
+			wantOK: true,
+		},
+		{
+			name: "missing",
+			dep:  "example.com/missing v1.0.0",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, ok := o.constructFilePath(test.dep)
+			if ok != test.wantOK {
+				t.Fatalf("constructFilePath(%q) found = %v, want %v", test.dep, ok, test.wantOK)
+			}
+			if want := path.Join(gopath, test.want); ok && got != want {
+				t.Errorf("constructFilePath(%q) = %q, want %q", test.dep, got, want)
+			}
+		})
+	}
+}
+
+func TestDefaultModCache(t *testing.T) {
+	gopath := strings.Join([]string{"/one", "", "/two"}, string(os.PathListSeparator))
+	tests := []struct {
+		name       string
+		gomodcache string
+		opts       []Option
+		want       string
+	}{
+		{
+			name:       "GOMODCACHE",
+			gomodcache: "/cache",
+			opts:       []Option{WithGopath(gopath)},
+			want:       "/cache",
+		},
+		{
+			name: "first GOPATH entry",
+			opts: []Option{WithGopath(gopath)},
+			want: "/one/pkg/mod",
+		},
+		{
+			name: "first non-empty GOPATH entry",
+			opts: []Option{WithGopath(string(os.PathListSeparator) + "/two")},
+			want: "/two/pkg/mod",
+		},
+		{
+			name:       "set explicitly",
+			gomodcache: "/cache",
+			opts:       []Option{WithGopath(gopath), WithModCache("/explicit")},
+			want:       "/explicit",
+		},
+		{
+			name: "nothing without go env",
+			opts: []Option{WithNoGoEnv(true)},
+			want: "",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			setenv(t, "GOMODCACHE", test.gomodcache)
+			if got := newOptions(test.opts...).ModCache; got != test.want {
+				t.Errorf("ModCache = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWithOptions(t *testing.T) {
+	parent := NewGraph(WithModCache("/cache"), WithProxyCache("/proxy"), WithMaxDepth(2))
+	// The derived graph takes the parent's module cache rather than working
+	// it out again from the environment.
+	setenv(t, "GOMODCACHE", "/other")
+	got := NewGraph(WithOptions(parent.Options()), WithMaxDepth(1)).Options()
+	if got.ModCache != "/cache" || got.ProxyCache != "/proxy" || got.MaxDepth != 1 {
+		t.Errorf("options = %+v, want the parent's with a max depth of 1", got)
+	}
+}
+
+func TestResolutionCandidates(t *testing.T) {
+	setenv(t, "GOMODCACHE", "")
+	gopath := strings.Join([]string{"/one", "/two"}, string(os.PathListSeparator))
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{
+			name: "mod cache from the first GOPATH entry",
+			opts: []Option{WithGopath(gopath)},
+			want: []string{
+				"/one/src/example.com/a",
+				"/two/src/example.com/a",
+				"/one/pkg/mod/example.com/a@v1.0.0",
+				"/two/pkg/mod/example.com/a@v1.0.0",
+			},
+		},
+		{
+			name: "GOMODCACHE before each GOPATH entry's pkg/mod",
+			opts: []Option{WithGopath(gopath), WithModCache("/cache")},
+			want: []string{
+				"/one/src/example.com/a",
+				"/two/src/example.com/a",
+				"/cache/example.com/a@v1.0.0",
+				"/one/pkg/mod/example.com/a@v1.0.0",
+				"/two/pkg/mod/example.com/a@v1.0.0",
+			},
+		},
+		{
+			name: "exact versions only",
+			opts: []Option{WithGopath(gopath), WithModCache("/cache"), WithExactVersions(true)},
+			want: []string{
+				"/cache/example.com/a@v1.0.0",
+				"/one/pkg/mod/example.com/a@v1.0.0",
+				"/two/pkg/mod/example.com/a@v1.0.0",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := newOptions(test.opts...).ResolutionCandidates("example.com/a v1.0.0")
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ResolutionCandidates() =\n%q\nwant\n%q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestEscapeCapitals(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "github.com/pkg/errors", want: "github.com/pkg/errors"},
+		{name: "github.com/BurntSushi/toml", want: "github.com/!burnt!sushi/toml"},
+		{name: "github.com/BurntSushi/toml v0.3.1", want: "github.com/!burnt!sushi/toml v0.3.1"},
+		{name: "github.com/Azure/go-autorest v1.0.0-RC1", want: "github.com/!azure/go-autorest v1.0.0-!r!c1"},
+		{name: "github.com/FOO/bar", want: "github.com/!f!o!o/bar"},
+		{name: "gopkg.in/Foo.v2 v2.1.0", want: "gopkg.in/!foo.v2 v2.1.0"},
+		{name: "gopkg.in/yaml.v3 v3.0.1", want: "gopkg.in/yaml.v3 v3.0.1"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := EscapeCapitals(test.name); got != test.want {
+				t.Errorf("EscapeCapitals(%q) = %q, want %q", test.name, got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeCapitals(t *testing.T) {
+	for _, modPath := range []string{"github.com/pkg/errors", "github.com/BurntSushi/toml", "github.com/FOO/bar", "gopkg.in/Foo.v2"} {
+		if got := UnescapeCapitals(EscapeCapitals(modPath)); got != modPath {
+			t.Errorf("UnescapeCapitals(EscapeCapitals(%q)) = %q", modPath, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package deptree
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// maxAttempts is how many times a read or download is tried before its
+// error is given up on.
+const maxAttempts = 4
+
+// retryBackoff is how long to wait before trying again the first time,
+// doubling with each attempt after.
+var retryBackoff = 100 * time.Millisecond
+
+// statusError is a response from a module proxy that's neither the file
+// asked for nor a sign the proxy doesn't have it.
+type statusError struct {
+	url    string
+	status string
+	code   int
+}
+
+func (e *statusError) Error() string {
+	return e.url + ": " + e.status
+}
+
+// withRetry calls op until it succeeds or fails with an error that isn't
+// worth trying again, at most maxAttempts times, backing off a little longer
+// after each failure. It stops waiting if ctx is cancelled.
+func withRetry(ctx context.Context, op func() error) error {
+	wait := retryBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = op()
+		if err == nil || attempt == maxAttempts || !retryable(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// retryable reports whether err is likely to go away by itself: running out
+// of file descriptors, a network timeout, or a proxy that's overloaded or
+// failing.
+func retryable(err error) bool {
+	if tooManyFiles(err) {
+		return true
+	}
+	var status *statusError
+	if errors.As(err, &status) {
+		return status.code == http.StatusTooManyRequests || status.code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// tooManyFiles reports whether err is from the process or the system
+// running out of file descriptors.
+func tooManyFiles(err error) bool {
+	return errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE)
+}
@@ -0,0 +1,80 @@
+package deptree
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func init() {
+	retryBackoff = 0
+}
+
+// failingReads returns a read failing with err the first failures times it's
+// called, along with how many times it has been called.
+func failingReads(failures int, err error) (func() error, *int) {
+	calls := 0
+	return func() error {
+		calls++
+		if calls <= failures {
+			return err
+		}
+		return nil
+	}, &calls
+}
+
+func TestWithRetry(t *testing.T) {
+	tooMany := &os.PathError{Op: "open", Path: "go.mod", Err: syscall.EMFILE}
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "succeeds", failures: 0, err: tooMany, wantCalls: 1},
+		{name: "out of descriptors then succeeds", failures: 2, err: tooMany, wantCalls: 3},
+		{name: "system out of descriptors", failures: 1, err: &os.PathError{Op: "open", Path: "go.mod", Err: syscall.ENFILE}, wantCalls: 2},
+		{name: "out of descriptors every time", failures: maxAttempts, err: tooMany, wantCalls: maxAttempts, wantErr: true},
+		{name: "proxy failing", failures: 1, err: &statusError{code: 502}, wantCalls: 2},
+		{name: "proxy refusing", failures: 1, err: &statusError{code: 403}, wantCalls: 1, wantErr: true},
+		{name: "missing file", failures: 1, err: &os.PathError{Op: "open", Path: "go.mod", Err: syscall.ENOENT}, wantCalls: 1, wantErr: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			op, calls := failingReads(test.failures, test.err)
+			err := withRetry(context.Background(), op)
+			if (err != nil) != test.wantErr {
+				t.Errorf("withRetry() error = %v, want error %v", err, test.wantErr)
+			}
+			if *calls != test.wantCalls {
+				t.Errorf("withRetry() tried %d times, want %d", *calls, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestGoModCacheForgetsTransientErrors(t *testing.T) {
+	c := newGoModCache()
+	file := t.TempDir() + "/go.mod"
+	tooMany := &os.PathError{Op: "open", Path: file, Err: syscall.EMFILE}
+	if _, err := c.load(file, func() (GoMod, error) { return GoMod{}, tooMany }); !errors.Is(err, syscall.EMFILE) {
+		t.Fatalf("load() error = %v, want EMFILE", err)
+	}
+	mod, err := c.load(file, func() (GoMod, error) { return GoMod{Name: "example.com/a"}, nil })
+	if err != nil || mod.Name != "example.com/a" {
+		t.Errorf("load() after EMFILE = %+v, %v, want the go.mod loaded again", mod, err)
+	}
+}
+
+func TestThrottleKeepsOneJob(t *testing.T) {
+	g := NewGraph(WithJobs(3))
+	for i := 0; i < 5; i++ {
+		g.prefetcher.throttle()
+	}
+	if g.prefetcher.limit != 1 {
+		t.Errorf("throttled to %d jobs, want 1", g.prefetcher.limit)
+	}
+}
@@ -0,0 +1,16 @@
+module example.com/app
+
+go 1.16
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.1.0 // indirect
+	example.com/local v0.0.0
+	example.com/missing v1.0.0
+	github.com/Upper/case v1.0.0
+	gopkg.in/Bar.v1 v1.0.0
+	gopkg.in/Foo.v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+replace example.com/local => ../local
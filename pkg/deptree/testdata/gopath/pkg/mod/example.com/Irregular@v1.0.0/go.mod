@@ -0,0 +1,3 @@
+module example.com/Irregular
+
+go 1.16
@@ -0,0 +1,3 @@
+module example.com/b
+
+require example.com/c v1.0.0
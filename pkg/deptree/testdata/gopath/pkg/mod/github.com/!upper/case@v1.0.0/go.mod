@@ -0,0 +1,3 @@
+module github.com/Upper/case
+
+require example.com/c v1.0.0
@@ -0,0 +1,3 @@
+module example.com/local
+
+require example.com/c v1.0.0
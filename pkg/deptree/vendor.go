@@ -0,0 +1,88 @@
+package deptree
+
+import (
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// vendoredModule is a module listed in vendor/modules.txt.
+type vendoredModule struct {
+	name     string
+	explicit bool
+}
+
+// readVendorModules reads the modules listed in the vendor/modules.txt in
+// dir, in the order they're listed. Each module starts with a
+// "# path version" line, with "=> replacement" appended if it's replaced, and
+// is marked "## explicit" if the main module's go.mod requires it.
+func readVendorModules(dir string) ([]vendoredModule, error) {
+	fileBytes, err := ioutil.ReadFile(path.Join(dir, "vendor", "modules.txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]vendoredModule, 0)
+	for _, line := range strings.Split(string(fileBytes), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "## "):
+			if len(modules) > 0 && strings.HasPrefix(strings.TrimPrefix(line, "## "), "explicit") {
+				modules[len(modules)-1].explicit = true
+			}
+		case strings.HasPrefix(line, "# "):
+			// Lines such as "# example.com/x => ../x" record a replacement
+			// of every version of a module, not a vendored module.
+			if fields := strings.Fields(line); len(fields) > 2 && fields[2] == "=>" {
+				continue
+			}
+			fields := strings.Fields(strings.Split(strings.TrimPrefix(line, "# "), "=>")[0])
+			if len(fields) == 0 {
+				continue
+			}
+			modules = append(modules, vendoredModule{name: strings.Join(fields, " ")})
+		}
+	}
+	return modules, nil
+}
+
+// listVendor builds the graph of a vendored module from its
+// vendor/modules.txt rather than the module cache. Vendored modules don't
+// keep their go.mod files, so there's no way to tell which module requires
+// which: every vendored module is a requirement of the root and has no
+// requirements of its own, and those the root's go.mod doesn't require
+// explicitly are marked indirect.
+func (g *Graph) listVendor(modPath string, vendored []vendoredModule) {
+	root := g.Index(modPath)
+	mod, err := g.opts.parseGoModFile(g.Dir)
+	if err != nil {
+		g.Errors[root] = err
+		return
+	}
+	g.RootRequires = len(mod.Requires)
+	g.Replaces = mod.Replaces
+	if mod.Deprecated != "" {
+		g.Deprecated[root] = mod.Deprecated
+	}
+	g.Metadata[root] = ModuleMetadata{Go: mod.Go, Toolchain: mod.Toolchain}
+	indirect := make(map[string]bool)
+	for _, require := range mod.Requires {
+		indirect[require.Name()] = require.Indirect
+	}
+
+	children := make([]int, 0, len(vendored))
+	for _, v := range vendored {
+		if modPath, _ := SplitModuleName(v.name); !g.opts.follows(modPath) {
+			continue
+		}
+		child := g.Index(v.name)
+		if !v.explicit || indirect[v.name] {
+			g.Indirect[Edge{From: root, To: child}] = struct{}{}
+		}
+		if _, ok := g.Packages[child]; !ok {
+			g.Packages[child] = make([]int, 0)
+		}
+		children = append(children, child)
+	}
+	g.Packages[root] = children
+}
@@ -0,0 +1,171 @@
+package deptree
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GoWork is a parsed go.work file. Uses holds the directory of every module
+// the workspace uses, in the order they're listed, relative to the go.work
+// if they're written that way.
+type GoWork struct {
+	Go       string
+	Uses     []string
+	Replaces []ReplaceDirective
+}
+
+// FindWorkspace returns the go.work file the go command would use for the
+// module in dir: the one GOWORK names, or else the go.work in dir or, if
+// walkUp is set, the nearest one in a parent directory. Like the go command,
+// a workspace is used even where dir has a go.mod of its own, and never if
+// GOWORK is off.
+func FindWorkspace(dir string, walkUp bool) (string, bool) {
+	switch gowork := os.Getenv("GOWORK"); gowork {
+	case "off":
+		return "", false
+	case "":
+	default:
+		if abs, err := filepath.Abs(gowork); err == nil {
+			gowork = abs
+		}
+		return gowork, true
+	}
+	for {
+		file := path.Join(dir, "go.work")
+		if _, err := os.Stat(file); err == nil {
+			return file, true
+		}
+		parent := path.Dir(dir)
+		if !walkUp || parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// ReadGoWork reads and parses the go.work file at file.
+func ReadGoWork(file string) (GoWork, error) {
+	fileBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return GoWork{}, err
+	}
+
+	work := GoWork{}
+	use := func(spec string) {
+		spec = strings.TrimSpace(strings.Split(spec, "//")[0])
+		if spec == "" {
+			return
+		}
+		work.Uses = append(work.Uses, strings.Trim(spec, "\"`"))
+	}
+	replace := func(spec string) {
+		if r, ok := parseReplace(spec); ok {
+			work.Replaces = append(work.Replaces, r)
+		}
+	}
+	block := ""
+	for _, line := range strings.Split(string(fileBytes), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case block != "" && line == ")":
+			block = ""
+		case block == "use":
+			use(line)
+		case block == "replace":
+			replace(line)
+		case line == "use (" || line == "replace (":
+			block = strings.TrimSuffix(line, " (")
+		case strings.HasPrefix(line, "use "):
+			use(strings.TrimPrefix(line, "use "))
+		case strings.HasPrefix(line, "replace "):
+			replace(strings.TrimPrefix(line, "replace "))
+		case strings.HasPrefix(line, "go "):
+			work.Go = strings.TrimSpace(strings.TrimPrefix(line, "go "))
+		}
+	}
+	return work, nil
+}
+
+// BuildWorkspace builds one dependency graph of every module the go.work file
+// at file uses. The root of the graph is the workspace, named after the file,
+// and requires each of its modules. As the go command does, each module is
+// read from its directory wherever in the graph it's required, whatever the
+// version, as though replaced by it. The replace directives of the go.work
+// apply, along with those of its modules that it doesn't override.
+func BuildWorkspace(file string, opts ...Option) (*Graph, error) {
+	work, err := ReadGoWork(file)
+	if err != nil {
+		return nil, err
+	}
+
+	g := NewGraph(opts...)
+	g.Dir = path.Dir(file)
+	if err := g.useWorkspace(work); err != nil {
+		return nil, err
+	}
+	g.List(path.Base(file), g.opts.MaxDepth)
+	return g.finish()
+}
+
+// useWorkspace sets the graph up to walk work, reading the go.mod of each
+// module it uses. The workspace root is walked as if it were a go.mod
+// requiring each module, replacing each by its directory, and carrying the
+// replace and exclude directives of the go.work and its modules.
+func (g *Graph) useWorkspace(work GoWork) error {
+	root := GoMod{Go: work.Go}
+	replaced := make(map[string]bool)
+	for _, r := range work.Replaces {
+		replaced[r.Old] = true
+	}
+	root.Replaces = append(root.Replaces, work.Replaces...)
+
+	g.workspace = make(map[string]struct{})
+	mods := make([]GoMod, 0, len(work.Uses))
+	for _, dir := range work.Uses {
+		mod, err := g.opts.parseGoModFile(ReplaceDirective{New: dir}.LocalDir(g.Dir))
+		if err != nil {
+			return err
+		}
+		if mod.Name == "" {
+			return fmt.Errorf("go.work: %s has no module path in its go.mod", dir)
+		}
+		if _, ok := g.workspace[mod.Name]; ok {
+			return fmt.Errorf("go.work: module %s appears multiple times in workspace", mod.Name)
+		}
+		g.workspace[mod.Name] = struct{}{}
+		replaced[mod.Name] = true
+		mods = append(mods, mod)
+		root.Requires = append(root.Requires, Require{Path: mod.Name})
+		root.Replaces = append(root.Replaces, ReplaceDirective{Old: mod.Name, New: localPath(dir)})
+		root.Excludes = append(root.Excludes, mod.Excludes...)
+	}
+	// The modules' own replacements come after, so they never win over the
+	// workspace's. Local ones are made relative to the go.work.
+	for pos, mod := range mods {
+		for _, r := range mod.Replaces {
+			if replaced[r.Old] {
+				continue
+			}
+			if r.IsLocal() && !path.IsAbs(r.New) {
+				r.New = localPath(path.Join(work.Uses[pos], r.New))
+			}
+			root.Replaces = append(root.Replaces, r)
+		}
+	}
+	g.work = &root
+	return nil
+}
+
+// localPath returns dir written so IsLocal recognises it as a directory,
+// starting with ./ unless it's absolute or already starts with a dot.
+func localPath(dir string) string {
+	dir = path.Clean(dir)
+	if path.IsAbs(dir) || dir == "." || dir == ".." || strings.HasPrefix(dir, "../") {
+		return dir
+	}
+	return "./" + dir
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	gomodule "golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// policy is a set of rules the resolved dependency tree has to follow, read
+// from the JSON file given to -policy.
+type policy struct {
+	// Banned holds module path patterns, in the format of GOPRIVATE, that
+	// mustn't appear in the tree.
+	Banned []string `json:"banned"`
+	// MaxVersions holds the highest version each module path may resolve to.
+	MaxVersions map[string]string `json:"maxVersions"`
+	// NoDuplicateMajors forbids resolving more than one major version of the
+	// same module, such as example.com/x and example.com/x/v2.
+	NoDuplicateMajors bool `json:"noDuplicateMajors"`
+}
+
+// policyViolation is a module breaking one of the rules of a policy, along
+// with the shortest path of requires pulling it in.
+type policyViolation struct {
+	Rule   string   `json:"rule"`
+	Module string   `json:"module"`
+	Detail string   `json:"detail"`
+	Path   []string `json:"path"`
+}
+
+// readPolicy reads and checks the policy in the JSON file at filePath.
+// Unknown rules are an error, so a misspelt rule isn't silently ignored.
+func readPolicy(filePath string) (policy, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return policy{}, err
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	var p policy
+	if err := decoder.Decode(&p); err != nil {
+		return policy{}, fmt.Errorf("reading policy %s: %v", filePath, err)
+	}
+	for modPath, version := range p.MaxVersions {
+		if !semver.IsValid(version) {
+			return policy{}, fmt.Errorf("reading policy %s: maximum version %q of %s isn't valid semver", filePath, version, modPath)
+		}
+	}
+	return p, nil
+}
+
+// policyViolations checks the version every module path resolves to, as
+// minimal version selection picks it, against the policy.
+func (m *module) policyViolations(p policy) []policyViolation {
+	violations := make([]policyViolation, 0)
+	add := func(rule, name, detail string) {
+		path := make([]string, 0)
+		if paths := m.shortestPaths(name); len(paths) > 0 {
+			for _, i := range paths[0] {
+				path = append(path, m.Indexes[i])
+			}
+		}
+		violations = append(violations, policyViolation{Rule: rule, Module: name, Detail: detail, Path: path})
+	}
+
+	banned := strings.Join(p.Banned, ",")
+	selected := make(map[string]string)
+	for _, r := range m.resolvedVersions() {
+		selected[r.Module] = r.Module + " " + r.Selected
+		name := r.Module + " " + r.Selected
+		if banned != "" && gomodule.MatchPrefixPatterns(banned, r.Module) {
+			add("banned", name, "matches a banned module pattern")
+		}
+		if max, ok := p.MaxVersions[r.Module]; ok && semver.Compare(r.Selected, max) > 0 {
+			add("maxVersion", name, "newer than the highest allowed version "+max)
+		}
+	}
+	if p.NoDuplicateMajors {
+		for _, paths := range m.duplicateMajors() {
+			names := make([]string, 0, len(paths))
+			for _, modPath := range paths {
+				if name, ok := selected[modPath]; ok {
+					names = append(names, name)
+				}
+			}
+			if len(names) < 2 {
+				continue
+			}
+			for _, name := range names {
+				add("noDuplicateMajors", name, "resolved alongside "+strings.Join(others(names, name), ", "))
+			}
+		}
+	}
+
+	sort.SliceStable(violations, func(a, b int) bool {
+		if violations[a].Module != violations[b].Module {
+			return violations[a].Module < violations[b].Module
+		}
+		return violations[a].Rule < violations[b].Rule
+	})
+	return violations
+}
+
+// others returns every name in names but name.
+func others(names []string, name string) []string {
+	rest := make([]string, 0, len(names)-1)
+	for _, other := range names {
+		if other != name {
+			rest = append(rest, other)
+		}
+	}
+	return rest
+}
+
+// printPolicyViolations writes a report of the given policy violations.
+func printPolicyViolations(w io.Writer, prefix string, violations []policyViolation) {
+	fmt.Fprintln(w, prefix+"Dependency policy violated:")
+	for _, v := range violations {
+		fmt.Fprintln(w, "  "+v.Rule+": "+v.Module+" "+v.Detail)
+		fmt.Fprintln(w, "    "+strings.Join(v.Path, " -> "))
+	}
+}
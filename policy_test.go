@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		wantCode   int
+		wantStderr string
+	}{
+		{
+			name:   "followed",
+			policy: `{"banned": ["example.com/banned"], "maxVersions": {"example.com/b": "v1.1.0"}}`,
+		},
+		{
+			name:     "violated",
+			policy:   `{"banned": ["example.com/c"], "maxVersions": {"example.com/b": "v1.0.0"}}`,
+			wantCode: 1,
+			wantStderr: `Dependency policy violated:
+  maxVersion: example.com/b v1.1.0 newer than the highest allowed version v1.0.0
+    example.com/app -> example.com/b v1.1.0
+  banned: example.com/c v1.0.0 matches a banned module pattern
+    example.com/app -> example.com/a v1.0.0 -> example.com/c v1.0.0
+`,
+		},
+		{
+			name:       "invalid version",
+			policy:     `{"maxVersions": {"example.com/b": "latest"}}`,
+			wantCode:   1,
+			wantStderr: `maximum version "latest" of example.com/b isn't valid semver`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "policy.json")
+			if err := ioutil.WriteFile(file, []byte(test.policy), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, stderr, code := run(t, "example.com/app", "-policy", file)
+			if code != test.wantCode {
+				t.Errorf("exit code = %d, want %d", code, test.wantCode)
+			}
+			if !strings.Contains(stderr, test.wantStderr) || (test.wantStderr == "" && stderr != "") {
+				t.Errorf("stderr =\n%s\nwant\n%s", stderr, test.wantStderr)
+			}
+		})
+	}
+}
+
+func TestPolicyDuplicateMajors(t *testing.T) {
+	m := newModule(deptree.NewGraph())
+	for _, name := range []string{
+		"example.com/app",
+		"example.com/x v1.2.0",
+		"example.com/x/v2 v2.0.0",
+		"example.com/y v1.0.0",
+	} {
+		m.Index(name)
+	}
+	m.Packages[0] = []int{1, 3}
+	m.Packages[1] = []int{}
+	m.Packages[3] = []int{2}
+	m.Packages[2] = []int{}
+
+	got := m.policyViolations(policy{NoDuplicateMajors: true})
+	want := []policyViolation{
+		{
+			Rule:   "noDuplicateMajors",
+			Module: "example.com/x v1.2.0",
+			Detail: "resolved alongside example.com/x/v2 v2.0.0",
+			Path:   []string{"example.com/app", "example.com/x v1.2.0"},
+		},
+		{
+			Rule:   "noDuplicateMajors",
+			Module: "example.com/x/v2 v2.0.0",
+			Detail: "resolved alongside example.com/x v1.2.0",
+			Path:   []string{"example.com/app", "example.com/y v1.0.0", "example.com/x/v2 v2.0.0"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("policyViolations() = %+v, want %+v", got, want)
+	}
+	if got := m.policyViolations(policy{}); len(got) != 0 {
+		t.Errorf("policyViolations() with no rules = %+v, want none", got)
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// reverseDependencies returns every module directly requiring a module with
+// the path target, whatever its version, mapped to the version it requires.
+func (m *module) reverseDependencies(target string) map[string]string {
+	requiredBy := make(map[string]string)
+	for parent, children := range m.Packages {
+		for _, child := range children {
+			if modPath, version := deptree.SplitModuleName(m.Indexes[child]); modPath == target {
+				requiredBy[m.Indexes[parent]] = version
+			}
+		}
+	}
+	return requiredBy
+}
+
+// writeReverseDependencies writes the modules requiring another, one per line
+// followed by the version each requires, or as a JSON object of the same.
+func writeReverseDependencies(w io.Writer, format string, requiredBy map[string]string) error {
+	if format == "json" {
+		return writeJSONValue(w, requiredBy, &map[string]string{})
+	}
+	names := make([]string, 0, len(requiredBy))
+	for name := range requiredBy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, err := fmt.Fprintln(w, name+" -> "+requiredBy[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependent is a module requiring another, directly or through other
+// modules, along with the shortest path of requires from the root to it.
+type dependent struct {
+	Module string   `json:"module"`
+	Direct bool     `json:"direct"`
+	Path   []string `json:"path"`
+}
+
+// dependents is every module requiring the modules with a path.
+type dependents struct {
+	Module     string      `json:"module"`
+	Count      int         `json:"count"`
+	Direct     int         `json:"direct"`
+	Dependents []dependent `json:"dependents"`
+}
+
+// transitiveDependents returns every module requiring a module matching
+// target, a module path optionally followed by a version, either directly or
+// through other modules. Knowing everything that pulls a module in is what
+// it takes to get rid of it.
+func (m *module) transitiveDependents(target string) dependents {
+	targetPath, targetVersion := deptree.NameAndVersion(target)
+	parents := make(map[int][]int)
+	for parent, children := range m.Packages {
+		for _, child := range children {
+			parents[child] = append(parents[child], parent)
+		}
+	}
+
+	// Walk up the graph from every match, noting the modules requiring a
+	// match themselves.
+	direct := make(map[int]bool)
+	seen := make(map[int]bool)
+	queue := make([]int, 0)
+	for i, name := range m.Indexes {
+		if modPath, version := deptree.SplitModuleName(name); i != 0 && modPath == targetPath && (targetVersion == "" || version == targetVersion) {
+			for _, parent := range parents[i] {
+				direct[parent] = true
+			}
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		for _, parent := range parents[i] {
+			if !seen[parent] {
+				seen[parent] = true
+				queue = append(queue, parent)
+			}
+		}
+	}
+
+	routes := m.shortestRoutes()
+	result := dependents{Module: target, Dependents: make([]dependent, 0, len(seen))}
+	for i := range seen {
+		// Everything is required by the root in the end, so it's only worth
+		// listing if it requires a match itself.
+		if i == 0 && !direct[i] {
+			continue
+		}
+		path := make([]string, 0)
+		for _, step := range routes[i] {
+			path = append(path, m.Indexes[step])
+		}
+		result.Dependents = append(result.Dependents, dependent{Module: m.Indexes[i], Direct: direct[i], Path: path})
+		if direct[i] {
+			result.Direct++
+		}
+	}
+	result.Count = len(result.Dependents)
+	sort.Slice(result.Dependents, func(a, b int) bool {
+		return result.Dependents[a].Module < result.Dependents[b].Module
+	})
+	return result
+}
+
+// shortestRoutes returns, for every module, one of the shortest paths of
+// requires from the root to it, through the first module reaching it.
+func (m *module) shortestRoutes() map[int][]int {
+	levels := m.WalkLevels(nil)
+	routes := map[int][]int{0: {0}}
+	for _, i := range levels.Order[1:] {
+		parent := routes[levels.Parents[i][0]]
+		route := make([]int, len(parent), len(parent)+1)
+		copy(route, parent)
+		routes[i] = append(route, i)
+	}
+	return routes
+}
+
+// writeDependents writes every module requiring another, marking those that
+// require it directly and following each with its shortest path from the
+// root, or as a JSON object of the same.
+func writeDependents(w io.Writer, format string, d dependents) error {
+	if format == "json" {
+		return writeJSONValue(w, d, &dependents{})
+	}
+	if _, err := fmt.Fprintf(w, "%d modules require %s, %d of them directly:\n", d.Count, d.Module, d.Direct); err != nil {
+		return err
+	}
+	for _, dep := range d.Dependents {
+		label := dep.Module
+		if dep.Direct {
+			label += " (direct)"
+		}
+		if _, err := fmt.Fprintln(w, "  "+label+"\n    "+strings.Join(dep.Path, " -> ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestReverse(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-reverse", "example.com/c")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `3 modules require example.com/c, 3 of them directly:
+  example.com/a v1.0.0 (direct)
+    example.com/app -> example.com/a v1.0.0
+  example.com/b v1.0.0 (direct)
+    example.com/app -> example.com/a v1.0.0 -> example.com/b v1.0.0
+  example.com/b v1.1.0 (direct)
+    example.com/app -> example.com/b v1.1.0
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestTransitiveDependents(t *testing.T) {
+	m := newModule(deptree.NewGraph())
+	for _, name := range []string{
+		"example.com/app",
+		"example.com/x v1.0.0",
+		"example.com/y v1.0.0",
+		"example.com/target v1.0.0",
+		"example.com/target v1.1.0",
+	} {
+		m.Index(name)
+	}
+	m.Packages[0] = []int{1, 4}
+	m.Packages[1] = []int{2}
+	m.Packages[2] = []int{3}
+	m.Packages[3] = []int{}
+	m.Packages[4] = []int{}
+
+	got := m.transitiveDependents("example.com/target@v1.0.0")
+	want := dependents{
+		Module: "example.com/target@v1.0.0",
+		Count:  2,
+		Direct: 1,
+		Dependents: []dependent{
+			{Module: "example.com/x v1.0.0", Path: []string{"example.com/app", "example.com/x v1.0.0"}},
+			{Module: "example.com/y v1.0.0", Direct: true, Path: []string{"example.com/app", "example.com/x v1.0.0", "example.com/y v1.0.0"}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transitiveDependents() = %+v, want %+v", got, want)
+	}
+
+	// Without a version every version matches, and the root is listed as it
+	// requires one of them itself.
+	if got := m.transitiveDependents("example.com/target"); got.Count != 3 || got.Direct != 2 {
+		t.Errorf("transitiveDependents() = %+v, want the root, x and y with two of them direct", got)
+	}
+}
+
+func TestReverseIsTransitiveRdeps(t *testing.T) {
+	reverse, stderr, code := run(t, "example.com/app", "-reverse", "example.com/c", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	transitive, stderr, code := run(t, "example.com/app", "-rdeps", "example.com/c", "-transitive", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	if reverse != transitive {
+		t.Errorf("-reverse output =\n%s\nwant the -rdeps -transitive output\n%s", reverse, transitive)
+	}
+}
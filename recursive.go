@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// findModuleRoots returns every directory under dir containing a go.mod,
+// skipping the directories the go command itself ignores along with vendor
+// and node_modules.
+func findModuleRoots(dir string) ([]string, error) {
+	roots := make([]string, 0)
+	err := filepath.Walk(dir, func(current string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		name := info.Name()
+		if current != dir && (name == "vendor" || name == "testdata" || name == "node_modules" ||
+			strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(current, "go.mod")); err == nil {
+			roots = append(roots, current)
+		}
+		return nil
+	})
+	return roots, err
+}
+
+// listRecursive builds the graph of every module found under dir, keyed by
+// the module's directory relative to dir. The graphs share parsed go.mod files
+// so dependencies common to several modules are only read once.
+func listRecursive(dir string, opts ...deptree.Option) (map[string]*module, []string, error) {
+	roots, err := findModuleRoots(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	return listModules(dir, roots, opts...)
+}
+
+// listModules builds the graph of the module in each of roots, at most -jobs
+// at a time, keyed by its directory relative to dir. A root given twice is
+// only built once.
+func listModules(dir string, roots []string, opts ...deptree.Option) (map[string]*module, []string, error) {
+	keys := make([]string, 0, len(roots))
+	unique := make([]string, 0, len(roots))
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		key, err := filepath.Rel(dir, root)
+		if err != nil {
+			return nil, nil, err
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+		unique = append(unique, root)
+	}
+
+	built := make([]*deptree.Graph, len(unique))
+	errs := make([]error, len(unique))
+	limit := make(chan struct{}, *jobs)
+	var wg sync.WaitGroup
+	for pos, root := range unique {
+		wg.Add(1)
+		go func(pos int, root string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			built[pos], errs[pos] = deptree.Build(root, opts...)
+		}(pos, root)
+	}
+	wg.Wait()
+
+	graphs := make(map[string]*module)
+	for pos, key := range keys {
+		if errs[pos] != nil {
+			return nil, nil, errs[pos]
+		}
+		graphs[key] = newModule(built[pos])
+	}
+	return graphs, keys, nil
+}
+
+// writeRecursive writes the graphs of several modules. JSON output is a single
+// document keyed by module directory, text output is each tree in turn.
+func writeRecursive(w io.Writer, format string, graphs map[string]*module, keys []string, depth int) error {
+	if format == "json" {
+		combined := make(map[string]jsonGraph)
+		for key, m := range graphs {
+			combined[key] = newJSONGraph(m)
+		}
+		return writeJSONValue(w, combined, &map[string]jsonGraph{})
+	}
+
+	for _, key := range keys {
+		if err := writeGraph(w, format, graphs[key], depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
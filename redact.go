@@ -0,0 +1,327 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+	"golang.org/x/mod/semver"
+)
+
+// redactor replaces private module paths with stable hashes.
+type redactor struct {
+	prefixes []string
+	versions bool
+}
+
+// hash returns a short stable hash of s.
+func (r redactor) hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:3])
+}
+
+// path redacts a module or package path starting with one of the prefixes.
+// The path is kept up to the last separator in the prefix and each segment
+// after that is replaced with a hash of the path up to that segment, so
+// "github.com/org/" keeps the organisation while "github.com/org/app" hides
+// app too. The same path always redacts the same way, and paths nested under
+// one another stay nested, so the graph keeps its shape.
+func (r redactor) path(p string) (string, bool) {
+	for _, prefix := range r.prefixes {
+		if !strings.HasPrefix(p, prefix) {
+			continue
+		}
+		kept := prefix[:strings.LastIndex(prefix, "/")+1]
+		segments := strings.Split(strings.TrimPrefix(p, kept), "/")
+		for i := range segments {
+			segments[i] = "internal-" + r.hash(kept+strings.Join(segments[:i+1], "/"))
+		}
+		return kept + strings.Join(segments, "/"), true
+	}
+	return p, false
+}
+
+// name redacts a module name as stored in Indexes, also redacting the version
+// of a private module if versions are redacted.
+func (r redactor) name(name string) string {
+	modPath, version := deptree.SplitModuleName(name)
+	redacted, private := r.path(modPath)
+	if !private {
+		return name
+	}
+	if version == "" {
+		return redacted
+	}
+	return redacted + " " + r.version(modPath, version)
+}
+
+// version redacts the version of the private module modPath if versions are
+// redacted.
+func (r redactor) version(modPath, version string) string {
+	if !r.versions || version == "" {
+		return version
+	}
+	return "redacted-" + r.hash(modPath+" "+version)
+}
+
+// pathEnd holds the characters that end a module path or version in free
+// text.
+const pathEnd = " \t\n\"'`@:,;()[]<>"
+
+// pathChars holds the characters a module path may contain besides
+// slashes, escaped as in the module cache.
+const pathChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789.-_~!"
+
+// text redacts every private module path in free text, such as an error or
+// deprecation message. Paths escaped as in the module cache are found too,
+// and a version following a private path, after a space or an @, is redacted
+// if versions are.
+func (r redactor) text(s string) string {
+	forms := make([]string, 0, 2*len(r.prefixes))
+	for _, prefix := range r.prefixes {
+		forms = append(forms, prefix, deptree.EscapeCapitals(prefix))
+	}
+	var b strings.Builder
+	for pos := 0; pos < len(s); {
+		matched := ""
+		// A path only starts where a word does, so example.com/ isn't
+		// found inside apexample.com/.
+		startsWord := pos == 0 || !strings.ContainsAny(s[pos-1:pos], pathChars)
+		for _, form := range forms {
+			if startsWord && strings.HasPrefix(s[pos:], form) {
+				matched = form
+				break
+			}
+		}
+		if matched == "" {
+			b.WriteByte(s[pos])
+			pos++
+			continue
+		}
+		end := pos + len(matched)
+		if n := strings.IndexAny(s[end:], pathEnd); n >= 0 {
+			end += n
+		} else {
+			end = len(s)
+		}
+		modPath := deptree.UnescapeCapitals(s[pos:end])
+		redacted, _ := r.path(modPath)
+		b.WriteString(redacted)
+		pos = end
+		if pos < len(s) && (s[pos] == ' ' || s[pos] == '@') {
+			end := pos + 1
+			if n := strings.IndexAny(s[end:], pathEnd+"/"); n >= 0 {
+				end += n
+			} else {
+				end = len(s)
+			}
+			if version := s[pos+1 : end]; semver.IsValid(version) {
+				b.WriteString(s[pos:pos+1] + r.version(modPath, version))
+				pos = end
+			}
+		}
+	}
+	return b.String()
+}
+
+// urlHost matches the scheme, credentials and host of a URL, such as those of
+// the module proxies in download errors.
+var urlHost = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s"]*`)
+
+// message redacts an error message about the graph of m. Besides private
+// module paths, it hides the directories of the module cache, GOPATH, the
+// proxy cache and the root module, which give away the user's name, and the
+// module proxy, whose URL may carry credentials.
+func (r redactor) message(m *module, message string) string {
+	o := m.Options()
+	dirs := map[string]string{
+		o.ModCache:   "$GOMODCACHE",
+		o.ProxyCache: "$PROXYCACHE",
+		m.Dir:        ".",
+	}
+	for _, gopath := range filepath.SplitList(o.Gopath) {
+		dirs[gopath] = "$GOPATH"
+	}
+	// Replace the longest directory first, so the module cache inside
+	// GOPATH is named as the module cache.
+	sorted := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		if dir != "" {
+			sorted = append(sorted, dir)
+		}
+	}
+	sort.Slice(sorted, func(a, b int) bool {
+		return len(sorted[a]) > len(sorted[b])
+	})
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, dir := range sorted {
+		pairs = append(pairs, dir, dirs[dir])
+	}
+	message = strings.NewReplacer(pairs...).Replace(message)
+	return r.text(urlHost.ReplaceAllString(message, "$$proxy"))
+}
+
+// redacted returns a copy of the graph with private module paths replaced by
+// stable hashes, throughout every field holding a module path, version or
+// message. Everything else is shared with m.
+func (m *module) redacted(r redactor) *module {
+	graph := *m.Graph
+	sub := *m
+	sub.Graph = &graph
+	sub.Indexes = make([]string, 0, len(m.Indexes))
+	sub.Lookup = make(map[string]int)
+	for i, name := range m.Indexes {
+		redacted := r.name(name)
+		sub.Indexes = append(sub.Indexes, redacted)
+		sub.Lookup[redacted] = i
+	}
+
+	sub.Errors = make(map[int]error)
+	for i, err := range m.Errors {
+		sub.Errors[i] = errors.New(r.message(m, err.Error()))
+	}
+	sub.Mismatches = make(map[int]string)
+	for i, declared := range m.Mismatches {
+		sub.Mismatches[i], _ = r.path(declared)
+	}
+	sub.Deprecated = make(map[int]string)
+	for i, message := range m.Deprecated {
+		sub.Deprecated[i] = r.text(message)
+	}
+
+	sub.Excludes = make(map[string]struct{})
+	for name := range m.Excludes {
+		sub.Excludes[r.name(name)] = struct{}{}
+	}
+	// Versions can't be resolved once they're redacted, so they're
+	// resolved first.
+	sub.resolved = make([]resolvedVersion, 0, len(m.resolvedVersions()))
+	for _, resolved := range m.resolvedVersions() {
+		if redacted, private := r.path(resolved.Module); private {
+			resolved.Selected = r.version(resolved.Module, resolved.Selected)
+			versions := make([]string, 0, len(resolved.Versions))
+			for _, version := range resolved.Versions {
+				versions = append(versions, r.version(resolved.Module, version))
+			}
+			resolved.Versions = versions
+			resolved.Module = redacted
+		}
+		sub.resolved = append(sub.resolved, resolved)
+	}
+
+	sub.Coalesced = make([]deptree.CoalescedRequire, 0, len(m.Coalesced))
+	for _, c := range m.Coalesced {
+		c.Parent = r.name(c.Parent)
+		if redacted, private := r.path(c.Module); private {
+			c.Required = r.version(c.Module, c.Required)
+			c.Selected = r.version(c.Module, c.Selected)
+			c.Module = redacted
+		}
+		sub.Coalesced = append(sub.Coalesced, c)
+	}
+	sub.imports = make([]packageImport, 0, len(m.imports))
+	for _, imp := range m.imports {
+		imp.Package, _ = r.path(imp.Package)
+		imp.Import, _ = r.path(imp.Import)
+		imp.PackageModule = r.name(imp.PackageModule)
+		imp.Module = r.name(imp.Module)
+		sub.imports = append(sub.imports, imp)
+	}
+	sub.rootPackages = make([]string, 0, len(m.rootPackages))
+	for _, pkg := range m.rootPackages {
+		pkg, _ = r.path(pkg)
+		sub.rootPackages = append(sub.rootPackages, pkg)
+	}
+	sub.skippedFiles = make([]skippedFile, 0, len(m.skippedFiles))
+	for _, f := range m.skippedFiles {
+		f.File, _ = r.path(f.File)
+		f.Error = r.message(m, f.Error)
+		sub.skippedFiles = append(sub.skippedFiles, f)
+	}
+	sub.vulns = make([]vulnerability, 0, len(m.vulns))
+	for _, v := range m.vulns {
+		v.Module = r.name(v.Module)
+		path := make([]string, 0, len(v.Path))
+		for _, name := range v.Path {
+			path = append(path, r.name(name))
+		}
+		v.Path = path
+		sub.vulns = append(sub.vulns, v)
+	}
+	if m.outdated != nil {
+		sub.outdated = make([]outdatedModule, 0, len(m.outdated))
+		for _, o := range m.outdated {
+			if redacted, private := r.path(o.Module); private {
+				o.Version = r.version(o.Module, o.Version)
+				o.Patch = r.version(o.Module, o.Patch)
+				o.Minor = r.version(o.Module, o.Minor)
+				retracted := make([]string, 0, len(o.Retracted))
+				for _, version := range o.Retracted {
+					retracted = append(retracted, r.version(o.Module, version))
+				}
+				o.Retracted = retracted
+				o.Module = redacted
+			}
+			o.Major = r.name(o.Major)
+			o.Deprecated = r.text(o.Deprecated)
+			sub.outdated = append(sub.outdated, o)
+		}
+	}
+	if m.sums != nil {
+		sums := &sumVerification{
+			Missing:     r.names(m.sums.Missing),
+			Pruned:      r.names(m.sums.Pruned),
+			Mismatched:  make([]hashMismatch, 0, len(m.sums.Mismatched)),
+			Unreachable: r.names(m.sums.Unreachable),
+		}
+		for _, mismatch := range m.sums.Mismatched {
+			mismatch.Module = r.name(mismatch.Module)
+			sums.Mismatched = append(sums.Mismatched, mismatch)
+		}
+		sub.sums = sums
+	}
+	sub.Replaces = make([]deptree.ReplaceDirective, 0, len(m.Replaces))
+	for _, replace := range m.Replaces {
+		sub.Replaces = append(sub.Replaces, r.replace(replace))
+	}
+	sub.Replaced = make(map[int]deptree.ReplaceDirective)
+	for i, replace := range m.Replaced {
+		sub.Replaced[i] = r.replace(replace)
+	}
+	return &sub
+}
+
+// names redacts each of names.
+func (r redactor) names(names []string) []string {
+	redacted := make([]string, 0, len(names))
+	for _, name := range names {
+		redacted = append(redacted, r.name(name))
+	}
+	return redacted
+}
+
+// replace redacts a replace directive. A private module replaced with a
+// directory has the directory hashed too, as it's usually named after the
+// module.
+func (r redactor) replace(replace deptree.ReplaceDirective) deptree.ReplaceDirective {
+	oldPath := replace.Old
+	replace.Old, _ = r.path(replace.Old)
+	if _, private := r.path(oldPath); private && replace.IsLocal() {
+		replace.New = "redacted-dir-" + r.hash(replace.New)
+		return replace
+	}
+	if _, private := r.path(oldPath); private {
+		replace.OldVersion = r.version(oldPath, replace.OldVersion)
+	}
+	newPath := replace.New
+	replace.New, _ = r.path(replace.New)
+	if _, private := r.path(newPath); private {
+		replace.NewVersion = r.version(newPath, replace.NewVersion)
+	}
+	return replace
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "paths",
+			args: []string{"-redact", "example.com/b"},
+			want: `example.com/app:
+  example.com/a v1.0.0:
+    example.com/internal-21e68f v1.0.0:
+      example.com/c v1.0.0:
+    example.com/c v1.0.0:
+  example.com/internal-21e68f v1.1.0:
+    example.com/c v1.0.0:
+  example.com/missing v1.0.0
+`,
+		},
+		{
+			name: "versions",
+			args: []string{"-redact", "example.com/b", "-redactVersions"},
+			want: `example.com/app:
+  example.com/a v1.0.0:
+    example.com/internal-21e68f redacted-2ac941:
+      example.com/c v1.0.0:
+    example.com/c v1.0.0:
+  example.com/internal-21e68f redacted-e17db2:
+    example.com/c v1.0.0:
+  example.com/missing v1.0.0
+`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stdout, stderr, code := run(t, "example.com/app", test.args...)
+			if code != 0 {
+				t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+			}
+			if stdout != test.want {
+				t.Errorf("output =\n%s\nwant\n%s", stdout, test.want)
+			}
+		})
+	}
+}
+
+func TestRedactorPath(t *testing.T) {
+	r := redactor{prefixes: []string{"github.com/org/"}}
+	app, private := r.path("github.com/org/app")
+	if !private {
+		t.Fatalf("path(github.com/org/app) isn't private")
+	}
+	pkg, _ := r.path("github.com/org/app/pkg")
+	if !strings.HasPrefix(pkg, app+"/") {
+		t.Errorf("path(github.com/org/app/pkg) = %q, want it nested under %q", pkg, app)
+	}
+	if again, _ := r.path("github.com/org/app"); again != app {
+		t.Errorf("path(github.com/org/app) = %q then %q, want it stable", app, again)
+	}
+	if public, private := r.path("github.com/other/app"); private || public != "github.com/other/app" {
+		t.Errorf("path(github.com/other/app) = %q, %v, want it unchanged", public, private)
+	}
+}
+
+func TestRedactGateReports(t *testing.T) {
+	tests := []struct {
+		name    string
+		modPath string
+		args    []string
+		private []string
+	}{
+		{
+			name:    "strict and clean tree",
+			modPath: "example.com/strict",
+			args:    []string{"-redact", "example.com/broken", "-strict", "-requireCleanTree"},
+			private: []string{"example.com/broken", "testdata"},
+		},
+		{
+			name:    "cycle",
+			modPath: "example.com/cyclic",
+			args:    []string{"-redact", "example.com/ping", "-failOnCycle"},
+			private: []string{"example.com/ping"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, stderr, code := run(t, test.modPath, test.args...)
+			if code != 1 {
+				t.Fatalf("exit code = %d, want 1, stderr:\n%s", code, stderr)
+			}
+			if !strings.Contains(stderr, "example.com/internal-") {
+				t.Errorf("stderr = %q, want the report to name the redacted module", stderr)
+			}
+			for _, private := range test.private {
+				if strings.Contains(stderr, private) {
+					t.Errorf("stderr = %q, want %q redacted", stderr, private)
+				}
+			}
+		})
+	}
+}
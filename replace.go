@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// unusedReplaces returns the root module's replace directives whose module
+// path isn't required anywhere in the graph. Only the main module's replaces
+// take effect, so those are the only ones checked.
+func (m *module) unusedReplaces() []deptree.ReplaceDirective {
+	required := make(map[string]struct{})
+	for _, name := range m.Indexes[1:] {
+		modPath, _ := deptree.SplitModuleName(name)
+		required[modPath] = struct{}{}
+	}
+
+	unused := make([]deptree.ReplaceDirective, 0)
+	for _, r := range m.Replaces {
+		if _, ok := required[r.Old]; !ok {
+			unused = append(unused, r)
+		}
+	}
+	return unused
+}
+
+// printUnusedReplaces writes the root module's replaces that aren't used.
+func printUnusedReplaces(w io.Writer, m *module) {
+	fmt.Fprintln(w, "Unused replaces:")
+	for _, r := range m.unusedReplaces() {
+		fmt.Fprintln(w, "  "+r.String())
+	}
+}
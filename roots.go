@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// pathList is a flag that may be given more than once, collecting every
+// value given. The default is dropped once a value is given.
+type pathList struct {
+	values []string
+	set    bool
+}
+
+// newPathList defines a pathList flag with the given name, default and
+// usage, like flag.String defines a string flag.
+func newPathList(name, value, usage string) *pathList {
+	l := &pathList{values: []string{value}}
+	flag.Var(l, name, usage)
+	return l
+}
+
+func (l *pathList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(l.values, ",")
+}
+
+func (l *pathList) Set(value string) error {
+	if !l.set {
+		l.values = nil
+		l.set = true
+	}
+	l.values = append(l.values, value)
+	return nil
+}
+
+// readPathsFile reads the paths listed in filePath, one per line, skipping
+// blank lines and those starting with #.
+func readPathsFile(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	paths := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, line)
+	}
+	return paths, scanner.Err()
+}
+
+// listRoots builds the graph of the module in each of roots, or with
+// recursive of every module found under each of them, keyed by its directory
+// relative to dir.
+func listRoots(dir string, roots []string, recursive bool, opts ...deptree.Option) (map[string]*module, []string, error) {
+	if recursive {
+		found := make([]string, 0)
+		for _, root := range roots {
+			under, err := findModuleRoots(root)
+			if err != nil {
+				return nil, nil, err
+			}
+			found = append(found, under...)
+		}
+		roots = found
+	}
+	return listModules(dir, roots, opts...)
+}
+
+// mergedReport is the JSON representation of the graphs of several modules
+// sharing a single module index, so a module every root requires is listed
+// once, along with the roots requiring each module.
+type mergedReport struct {
+	SchemaVersion int `json:"schemaVersion"`
+	// Indexes holds every module of every graph, which the graph of each
+	// root refers to by position.
+	Indexes []string     `json:"indexes"`
+	Roots   []mergedRoot `json:"roots"`
+	// UsedBy holds the directories of the roots whose graph has each module,
+	// keyed by module name.
+	UsedBy map[string][]string `json:"usedBy"`
+}
+
+// mergedRoot is the graph of one root of a mergedReport. Module is the
+// position of the root module in the shared index, and the graph lists no
+// indexes of its own.
+type mergedRoot struct {
+	Dir    string    `json:"dir"`
+	Module int       `json:"module"`
+	Graph  jsonGraph `json:"graph"`
+}
+
+// newMergedReport merges the graphs of several modules, keyed by directory,
+// into one report sharing a single module index.
+func newMergedReport(graphs map[string]*module, keys []string) mergedReport {
+	report := mergedReport{
+		SchemaVersion: jsonSchemaVersion,
+		Indexes:       make([]string, 0),
+		Roots:         make([]mergedRoot, 0, len(keys)),
+		UsedBy:        make(map[string][]string),
+	}
+	shared := make(map[string]int)
+	index := func(name string) int {
+		if i, ok := shared[name]; ok {
+			return i
+		}
+		shared[name] = len(report.Indexes)
+		report.Indexes = append(report.Indexes, name)
+		return shared[name]
+	}
+
+	for _, key := range keys {
+		m := graphs[key]
+		graph := newJSONGraph(m)
+		graph.SchemaVersion = 0
+		graph.Indexes = nil
+		// Index in graph order, so the shared index is the same every run.
+		for _, name := range m.Indexes {
+			index(name)
+		}
+		graph.Packages = make(map[int][]int)
+		for parent, children := range m.Packages {
+			mapped := make([]int, 0, len(children))
+			for _, child := range children {
+				mapped = append(mapped, index(m.Indexes[child]))
+			}
+			graph.Packages[index(m.Indexes[parent])] = mapped
+		}
+		for pos, i := range graph.Unknown {
+			graph.Unknown[pos] = index(m.Indexes[i])
+		}
+		for _, name := range m.Indexes[1:] {
+			report.UsedBy[name] = append(report.UsedBy[name], key)
+		}
+		report.Roots = append(report.Roots, mergedRoot{
+			Dir:    key,
+			Module: index(m.Indexes[0]),
+			Graph:  graph,
+		})
+	}
+	for _, dirs := range report.UsedBy {
+		sort.Strings(dirs)
+	}
+	return report
+}
+
+// writeMerged writes the graphs of several modules given on the command
+// line. JSON output is a single report sharing one module index, text
+// output is each tree in turn followed by the roots requiring each module.
+func writeMerged(w io.Writer, format string, graphs map[string]*module, keys []string, depth int) error {
+	if format == "json" {
+		return writeJSONValue(w, newMergedReport(graphs, keys), &mergedReport{})
+	}
+
+	for _, key := range keys {
+		if err := writeGraph(w, format, graphs[key], depth); err != nil {
+			return err
+		}
+	}
+	usedBy := newMergedReport(graphs, keys).UsedBy
+	names := make([]string, 0, len(usedBy))
+	for name := range usedBy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "Used by:")
+	for _, name := range names {
+		fmt.Fprintln(w, "  "+name+": "+strings.Join(usedBy[name], ", "))
+	}
+	return nil
+}
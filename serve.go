@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// jsonNode is a module and its immediate neighbours in the graph.
+type jsonNode struct {
+	Module     string   `json:"module"`
+	Depth      int      `json:"depth"`
+	Requires   []string `json:"requires"`
+	RequiredBy []string `json:"requiredBy"`
+	Unknown    bool     `json:"unknown,omitempty"`
+	Replaced   string   `json:"replaced,omitempty"`
+	License    string   `json:"license,omitempty"`
+}
+
+// newServer returns the handler of -serve, which serves a page exploring
+// the graph and the JSON endpoints it's built on:
+//
+//	/graph                  the graph as the json format writes it
+//	/node/<module>          a module and its neighbours
+//	/paths?to=<module>      the shortest paths from the root to a module
+//
+// Modules may be given as either path@version or a path alone, which
+// matches every version of it.
+func newServer(m *module) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, serverPage)
+	})
+	mux.HandleFunc("/graph", func(w http.ResponseWriter, r *http.Request) {
+		serveJSON(w, newJSONGraph(m))
+	})
+	mux.HandleFunc("/node/", func(w http.ResponseWriter, r *http.Request) {
+		nodes := m.nodes(strings.TrimPrefix(r.URL.Path, "/node/"))
+		if len(nodes) == 0 {
+			http.Error(w, "module not in the dependency tree", http.StatusNotFound)
+			return
+		}
+		serveJSON(w, nodes)
+	})
+	mux.HandleFunc("/paths", func(w http.ResponseWriter, r *http.Request) {
+		paths := make([][]string, 0)
+		for _, path := range m.shortestPaths(r.URL.Query().Get("to")) {
+			names := make([]string, 0, len(path))
+			for _, i := range path {
+				names = append(names, m.Indexes[i])
+			}
+			paths = append(paths, names)
+		}
+		serveJSON(w, paths)
+	})
+	return mux
+}
+
+// nodes returns every module matching target, a module path optionally
+// followed by a version, with its neighbours.
+func (m *module) nodes(target string) []jsonNode {
+	targetPath, targetVersion := deptree.NameAndVersion(target)
+	parents := make(map[int][]string)
+	for parent, children := range m.Packages {
+		for _, child := range children {
+			parents[child] = append(parents[child], m.Indexes[parent])
+		}
+	}
+	depths := m.Depths()
+
+	nodes := make([]jsonNode, 0)
+	for i, name := range m.Indexes {
+		modPath, version := deptree.SplitModuleName(name)
+		if modPath != targetPath || (targetVersion != "" && version != targetVersion) {
+			continue
+		}
+		node := jsonNode{
+			Module:     name,
+			Depth:      depths[i],
+			Requires:   make([]string, 0, len(m.Packages[i])),
+			RequiredBy: parents[i],
+			License:    m.licenses[i],
+		}
+		if node.RequiredBy == nil {
+			node.RequiredBy = make([]string, 0)
+		}
+		sort.Strings(node.RequiredBy)
+		for _, child := range m.Packages[i] {
+			node.Requires = append(node.Requires, m.Indexes[child])
+		}
+		if _, ok := m.Unknown[i]; ok {
+			node.Unknown = true
+		}
+		if r, ok := m.Replaced[i]; ok {
+			node.Replaced = strings.TrimSpace(r.New + " " + r.NewVersion)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// serveJSON writes v as the JSON response.
+func serveJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// serverPage is the page -serve explores the graph with. Each module's
+// requirements are only drawn when it's expanded, so even large graphs with
+// many shared subtrees open instantly.
+const serverPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Dependency tree</title>
+<style>
+body { font-family: sans-serif; margin: 1em 2em; }
+#search { width: 30em; padding: 0.3em; }
+#tree, #details { font-family: monospace; }
+ul { list-style: none; padding-left: 1.5em; margin: 0; }
+li > span { cursor: pointer; }
+li > span:hover { text-decoration: underline; }
+.toggle { display: inline-block; width: 1em; color: #888; }
+.unknown { color: #a00; font-style: italic; }
+.match { background: #ff0; }
+.main { display: flex; gap: 2em; }
+#details { min-width: 30em; white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1 id="root"></h1>
+<p><input id="search" placeholder="Search modules, then press enter to show the paths to them"></p>
+<div class="main"><div id="tree"></div><div id="details"></div></div>
+<script>
+let graph;
+
+function node(i, ancestors) {
+  const li = document.createElement("li");
+  const toggle = document.createElement("span");
+  toggle.className = "toggle";
+  const label = document.createElement("span");
+  label.textContent = graph.indexes[i];
+  if (graph.unknown.includes(i)) label.className = "unknown";
+  label.dataset.name = graph.indexes[i];
+  label.onclick = () => showNode(graph.indexes[i]);
+  li.append(toggle, label);
+  const children = graph.packages[i] || [];
+  if (children.length > 0 && !ancestors.has(i)) {
+    toggle.textContent = "+";
+    let list = null;
+    toggle.onclick = () => {
+      if (list) {
+        list.hidden = !list.hidden;
+      } else {
+        list = document.createElement("ul");
+        const next = new Set(ancestors).add(i);
+        children.forEach(c => list.append(node(c, next)));
+        li.append(list);
+        highlight();
+      }
+      toggle.textContent = list.hidden ? "+" : "-";
+    };
+  }
+  return li;
+}
+
+async function showNode(name) {
+  const resp = await fetch("/node/" + encodeURIComponent(name));
+  document.getElementById("details").textContent = JSON.stringify(await resp.json(), null, 2);
+}
+
+async function showPaths(name) {
+  const resp = await fetch("/paths?to=" + encodeURIComponent(name));
+  const paths = await resp.json();
+  document.getElementById("details").textContent = paths.length === 0
+    ? name + " isn't in the dependency tree"
+    : paths.map(p => p.join("\n  -> ")).join("\n\n");
+}
+
+function highlight() {
+  const query = document.getElementById("search").value.trim();
+  document.querySelectorAll("#tree li > span[data-name]").forEach(s => {
+    s.classList.toggle("match", query !== "" && s.dataset.name.includes(query));
+  });
+}
+
+fetch("/graph").then(r => r.json()).then(g => {
+  graph = g;
+  document.getElementById("root").textContent = g.indexes[0];
+  const list = document.createElement("ul");
+  (g.packages[0] || []).forEach(c => list.append(node(c, new Set([0]))));
+  document.getElementById("tree").append(list);
+});
+const search = document.getElementById("search");
+search.oninput = highlight;
+search.onkeydown = e => { if (e.key === "Enter") showPaths(search.value.trim()); };
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// spdxDocument is the subset of an SPDX 2.3 JSON document needed to describe
+// the modules in the graph and how they depend on one another.
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name             string `json:"name"`
+	SPDXID           string `json:"SPDXID"`
+	VersionInfo      string `json:"versionInfo,omitempty"`
+	DownloadLocation string `json:"downloadLocation"`
+	LicenseDeclared  string `json:"licenseDeclared,omitempty"`
+	FilesAnalyzed    bool   `json:"filesAnalyzed"`
+	// Comment carries the go.sum hash, which is a hash of the module's file
+	// tree rather than of any one artifact, so isn't an SPDX checksum.
+	Comment      string            `json:"comment,omitempty"`
+	ExternalRefs []spdxExternalRef `json:"externalRefs"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxID returns the SPDX identifier of the module at index i. Module paths
+// can hold characters SPDX identifiers can't, so modules are identified by
+// their index instead.
+func spdxID(i int) string {
+	return "SPDXRef-Package-" + strconv.Itoa(i)
+}
+
+// buildSPDX builds an SPDX document of the graph, describing the root module.
+func buildSPDX(m *module) (spdxDocument, error) {
+	namespace, err := randomUUID()
+	if err != nil {
+		return spdxDocument{}, err
+	}
+	sums := readGoSumH1(m.Dir)
+
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              m.Indexes[0],
+		DocumentNamespace: "https://spdx.org/spdxdocs/" + m.Indexes[0] + "-" + namespace,
+		CreationInfo: spdxCreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []string{"Tool: go-mod-dependency-tree"},
+		},
+		Packages: make([]spdxPackage, 0, len(m.Indexes)),
+		Relationships: []spdxRelationship{{
+			SPDXElementID:      "SPDXRef-DOCUMENT",
+			RelationshipType:   "DESCRIBES",
+			RelatedSPDXElement: spdxID(0),
+		}},
+	}
+	for i, name := range m.Indexes {
+		modPath, version := deptree.SplitModuleName(name)
+		pkg := spdxPackage{
+			Name:             modPath,
+			SPDXID:           spdxID(i),
+			VersionInfo:      version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl(name),
+			}},
+		}
+		if license, ok := m.licenses[i]; ok {
+			pkg.LicenseDeclared = "NOASSERTION"
+			if license != unknownLicense {
+				pkg.LicenseDeclared = license
+			}
+		}
+		if hash, ok := sums[name]; ok {
+			pkg.Comment = "go.sum h1: " + hash
+		}
+		doc.Packages = append(doc.Packages, pkg)
+
+		for _, child := range m.Packages[i] {
+			doc.Relationships = append(doc.Relationships, spdxRelationship{
+				SPDXElementID:      spdxID(i),
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: spdxID(child),
+			})
+		}
+	}
+	return doc, nil
+}
+
+// writeSPDX writes the graph as an SPDX 2.3 JSON document.
+func writeSPDX(w io.Writer, m *module) error {
+	doc, err := buildSPDX(m)
+	if err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(b))
+	return err
+}
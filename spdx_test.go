@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSPDX(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/clean", "-format", "spdx")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var doc spdxDocument
+	if err := json.Unmarshal([]byte(stdout), &doc); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, stdout)
+	}
+	if doc.SPDXVersion != "SPDX-2.3" || doc.Name != "example.com/clean" {
+		t.Errorf("document = %q %q, want an SPDX-2.3 document named example.com/clean", doc.SPDXVersion, doc.Name)
+	}
+	if !strings.HasPrefix(doc.DocumentNamespace, "https://spdx.org/spdxdocs/example.com/clean-") {
+		t.Errorf("namespace = %q, want one under the module's name", doc.DocumentNamespace)
+	}
+	wantPackages := []spdxPackage{
+		{
+			Name:             "example.com/clean",
+			SPDXID:           "SPDXRef-Package-0",
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{
+				{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: "pkg:golang/example.com/clean"},
+			},
+		},
+		{
+			Name:             "example.com/c",
+			SPDXID:           "SPDXRef-Package-1",
+			VersionInfo:      "v1.0.0",
+			DownloadLocation: "NOASSERTION",
+			Comment:          "go.sum h1: h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=",
+			ExternalRefs: []spdxExternalRef{
+				{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: "pkg:golang/example.com/c@v1.0.0"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(doc.Packages, wantPackages) {
+		t.Errorf("packages = %+v, want %+v", doc.Packages, wantPackages)
+	}
+	wantRelationships := []spdxRelationship{
+		{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: "SPDXRef-Package-0"},
+		{SPDXElementID: "SPDXRef-Package-0", RelationshipType: "DEPENDS_ON", RelatedSPDXElement: "SPDXRef-Package-1"},
+	}
+	if !reflect.DeepEqual(doc.Relationships, wantRelationships) {
+		t.Errorf("relationships = %+v, want %+v", doc.Relationships, wantRelationships)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// topDegrees is how many modules the stats list by fan-in and fan-out.
+const topDegrees = 10
+
+// buildListTolerance is the fraction by which the number of modules the root
+// requires and the number reached can differ before it's flagged.
+const buildListTolerance = 0.1
+
+// treeStats summarises how much of the build list the walk reached.
+type treeStats struct {
+	// RootRequires is the number of modules in the root go.mod's require
+	// block, which for go 1.17 and later modules is the whole build list.
+	RootRequires int `json:"rootRequires"`
+	// Reached is the number of distinct module paths reached by the walk.
+	Reached int `json:"reached"`
+	// Discrepancy is set when RootRequires and Reached differ by more than
+	// buildListTolerance.
+	Discrepancy bool `json:"discrepancy"`
+	// Modules is the number of modules in the graph, counting each version
+	// separately and leaving out the root.
+	Modules int `json:"modules"`
+	// Direct is the number of modules the root requires without marking them
+	// // indirect, and Indirect the number of every other module.
+	Direct   int `json:"direct"`
+	Indirect int `json:"indirect"`
+	// MaxDepth is the most requires separating a module from the root by
+	// its shortest path.
+	MaxDepth int `json:"maxDepth"`
+	// TopFanIn and TopFanOut are the modules with the most dependents and
+	// dependencies, most first.
+	TopFanIn  []moduleDegree `json:"topFanIn"`
+	TopFanOut []moduleDegree `json:"topFanOut"`
+}
+
+// stats returns the summary of the graph.
+func (m *module) stats() treeStats {
+	reached := make(map[string]struct{})
+	for _, name := range m.Indexes[1:] {
+		modPath, _ := deptree.SplitModuleName(name)
+		reached[modPath] = struct{}{}
+	}
+
+	stats := treeStats{
+		RootRequires: m.RootRequires,
+		Reached:      len(reached),
+	}
+	larger, difference := stats.RootRequires, stats.Reached-stats.RootRequires
+	if stats.Reached > larger {
+		larger = stats.Reached
+	}
+	if difference < 0 {
+		difference = -difference
+	}
+	stats.Discrepancy = float64(difference) > buildListTolerance*float64(larger)
+
+	direct, _ := m.directRequires()
+	stats.Modules = len(m.Indexes) - 1
+	stats.Direct = len(direct)
+	stats.Indirect = stats.Modules - stats.Direct
+	for _, depth := range m.Depths() {
+		if depth > stats.MaxDepth {
+			stats.MaxDepth = depth
+		}
+	}
+	in, out := m.degrees()
+	stats.TopFanIn = m.topDegrees(in)
+	stats.TopFanOut = m.topDegrees(out)
+	return stats
+}
+
+// degrees returns the number of dependents and of dependencies of every
+// module, by index.
+func (m *module) degrees() ([]int, []int) {
+	in := make([]int, len(m.Indexes))
+	out := make([]int, len(m.Indexes))
+	for i := range m.Indexes {
+		out[i] = len(m.Packages[i])
+		for _, child := range m.Packages[i] {
+			in[child]++
+		}
+	}
+	return in, out
+}
+
+// topDegrees returns the topDegrees modules with the highest of the given
+// degrees, highest first, leaving out any of degree 0.
+func (m *module) topDegrees(degrees []int) []moduleDegree {
+	top := make([]moduleDegree, 0, len(degrees))
+	for i, degree := range degrees {
+		if degree > 0 {
+			top = append(top, moduleDegree{Module: m.Indexes[i], Degree: degree})
+		}
+	}
+	sort.Slice(top, func(a, b int) bool {
+		if top[a].Degree != top[b].Degree {
+			return top[a].Degree > top[b].Degree
+		}
+		return top[a].Module < top[b].Module
+	})
+	if len(top) > topDegrees {
+		top = top[:topDegrees]
+	}
+	return top
+}
+
+// printStats writes the summary of the graph.
+func printStats(w io.Writer, m *module) {
+	stats := m.stats()
+	fmt.Fprintln(w, "Stats:")
+	fmt.Fprintf(w, "  Root requires: %d\n", stats.RootRequires)
+	fmt.Fprintf(w, "  Modules reached: %d\n", stats.Reached)
+	if stats.Discrepancy {
+		fmt.Fprintln(w, "  The root requires and modules reached differ by more than 10%, either the go.mod is stale or modules failed to resolve")
+	}
+	fmt.Fprintf(w, "  Modules: %d (%d direct, %d indirect)\n", stats.Modules, stats.Direct, stats.Indirect)
+	fmt.Fprintf(w, "  Max depth: %d\n", stats.MaxDepth)
+	fmt.Fprintln(w, "  Top fan-in:")
+	for _, d := range stats.TopFanIn {
+		fmt.Fprintf(w, "    %s (%d)\n", d.Module, d.Degree)
+	}
+	fmt.Fprintln(w, "  Top fan-out:")
+	for _, d := range stats.TopFanOut {
+		fmt.Fprintf(w, "    %s (%d)\n", d.Module, d.Degree)
+	}
+}
+
+// graphStats describes the shape of the graph through its degree
+// distributions.
+type graphStats struct {
+	// InDegree maps a number of dependents to how many modules have it.
+	InDegree map[int]int `json:"inDegree"`
+	// OutDegree maps a number of dependencies to how many modules have it.
+	OutDegree map[int]int `json:"outDegree"`
+	// AverageDegree is the average number of dependencies of a module, which
+	// is also the average number of dependents.
+	AverageDegree float64      `json:"averageDegree"`
+	MaxFanOut     moduleDegree `json:"maxFanOut"`
+	MaxFanIn      moduleDegree `json:"maxFanIn"`
+}
+
+// moduleDegree is a module and its number of dependencies or dependents.
+type moduleDegree struct {
+	Module string `json:"module"`
+	Degree int    `json:"degree"`
+}
+
+// graphStats returns the degree distributions of the graph.
+func (m *module) graphStats() graphStats {
+	in, out := m.degrees()
+	edges := 0
+	for _, degree := range out {
+		edges += degree
+	}
+
+	stats := graphStats{
+		InDegree:      make(map[int]int),
+		OutDegree:     make(map[int]int),
+		AverageDegree: float64(edges) / float64(len(m.Indexes)),
+	}
+	for i, name := range m.Indexes {
+		stats.InDegree[in[i]]++
+		stats.OutDegree[out[i]]++
+		if i == 0 || out[i] > stats.MaxFanOut.Degree {
+			stats.MaxFanOut = moduleDegree{Module: name, Degree: out[i]}
+		}
+		if i == 0 || in[i] > stats.MaxFanIn.Degree {
+			stats.MaxFanIn = moduleDegree{Module: name, Degree: in[i]}
+		}
+	}
+	return stats
+}
+
+// printGraphStats writes the degree distributions of the graph.
+func printGraphStats(w io.Writer, m *module) {
+	stats := m.graphStats()
+	fmt.Fprintln(w, "Graph stats:")
+	fmt.Fprintf(w, "  Average degree: %.2f\n", stats.AverageDegree)
+	fmt.Fprintf(w, "  Max fan-out: %s (%d)\n", stats.MaxFanOut.Module, stats.MaxFanOut.Degree)
+	fmt.Fprintf(w, "  Max fan-in: %s (%d)\n", stats.MaxFanIn.Module, stats.MaxFanIn.Degree)
+	fmt.Fprintln(w, "  Out-degree distribution:")
+	printDistribution(w, stats.OutDegree)
+	fmt.Fprintln(w, "  In-degree distribution:")
+	printDistribution(w, stats.InDegree)
+}
+
+// printDistribution writes how many modules have each degree, lowest first.
+func printDistribution(w io.Writer, distribution map[int]int) {
+	degrees := make([]int, 0, len(distribution))
+	for degree := range distribution {
+		degrees = append(degrees, degree)
+	}
+	sort.Ints(degrees)
+	for _, degree := range degrees {
+		fmt.Fprintf(w, "    %d: %d modules\n", degree, distribution[degree])
+	}
+}
@@ -0,0 +1,8 @@
+module example.com/a
+
+go 1.16
+
+require (
+	example.com/b v1.0.0
+	example.com/c v1.0.0
+)
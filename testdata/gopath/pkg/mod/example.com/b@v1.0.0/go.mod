@@ -0,0 +1,7 @@
+module example.com/b
+
+go 1.16
+
+require (
+	example.com/c v1.0.0
+)
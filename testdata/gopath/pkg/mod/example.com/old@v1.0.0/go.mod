@@ -0,0 +1,7 @@
+// Package old is no longer maintained.
+//
+// Deprecated: use example.com/c instead,
+// which does everything old did.
+module example.com/old
+
+go 1.16
@@ -0,0 +1,5 @@
+module example.com/ping
+
+go 1.16
+
+require example.com/pong v1.0.0
@@ -0,0 +1,5 @@
+module gopkg.in/Foo.v2
+
+require (
+	gopkg.in/yaml.v3 v3.0.1
+)
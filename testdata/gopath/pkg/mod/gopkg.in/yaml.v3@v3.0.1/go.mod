@@ -0,0 +1 @@
+module gopkg.in/yaml.v3
@@ -0,0 +1,9 @@
+module example.com/app
+
+go 1.16
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.1.0 // indirect
+	example.com/missing v1.0.0
+)
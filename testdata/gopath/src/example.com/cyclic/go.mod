@@ -0,0 +1,5 @@
+module example.com/cyclic
+
+go 1.16
+
+require example.com/ping v1.0.0
@@ -0,0 +1,5 @@
+package main
+
+import _ "example.com/imports/internal/x"
+
+func main() {}
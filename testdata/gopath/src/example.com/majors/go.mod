@@ -0,0 +1,8 @@
+module example.com/majors
+
+go 1.16
+
+require (
+	example.com/b v1.1.0
+	example.com/c/v2 v2.0.0
+)
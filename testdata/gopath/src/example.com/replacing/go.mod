@@ -0,0 +1,12 @@
+module example.com/replacing
+
+go 1.16
+
+require (
+	example.com/a v1.0.0
+	example.com/b v1.1.0
+)
+
+replace example.com/b v1.1.0 => ../clean
+
+exclude example.com/c v1.0.0
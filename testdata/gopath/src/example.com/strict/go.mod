@@ -0,0 +1,8 @@
+module example.com/strict
+
+go 1.16
+
+require (
+	example.com/broken v1.0.0
+	example.com/c v1.0.0
+)
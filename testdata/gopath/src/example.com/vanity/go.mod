@@ -0,0 +1,7 @@
+module example.com/vanity
+
+require (
+	gopkg.in/Bar.v1 v1.0.0
+	gopkg.in/Foo.v2 v2.1.0
+	gopkg.in/yaml.v3 v3.0.1
+)
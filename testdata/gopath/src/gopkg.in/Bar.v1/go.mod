@@ -0,0 +1 @@
+module gopkg.in/Bar.v1
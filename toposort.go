@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// topologicalOrder returns the modules of the graph ordered so that every
+// module comes after the modules it requires, using Kahn's algorithm. Modules
+// in a cycle can't be ordered, so each cycle is treated as one group whose
+// members are listed together in index order. Groups that could go in either
+// order are taken lowest index first, so the order is stable between runs.
+func (m *module) topologicalOrder() [][]int {
+	components, componentOf := m.stronglyConnectedComponents()
+	for _, members := range components {
+		sort.Ints(members)
+	}
+
+	remaining := make([]int, len(components))
+	dependents := make([][]int, len(components))
+	for c, members := range components {
+		required := make(map[int]bool)
+		for _, i := range members {
+			for _, child := range m.Packages[i] {
+				if d := componentOf[child]; d != c && !required[d] {
+					required[d] = true
+					remaining[c]++
+					dependents[d] = append(dependents[d], c)
+				}
+			}
+		}
+	}
+
+	// Ready groups are kept sorted by their lowest member.
+	ready := make([]int, 0)
+	for c := range components {
+		if remaining[c] == 0 {
+			ready = append(ready, c)
+		}
+	}
+	order := make([][]int, 0, len(components))
+	for len(ready) > 0 {
+		sort.Slice(ready, func(a, b int) bool {
+			return components[ready[a]][0] < components[ready[b]][0]
+		})
+		c := ready[0]
+		ready = ready[1:]
+		order = append(order, components[c])
+		for _, d := range dependents[c] {
+			remaining[d]--
+			if remaining[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+	return order
+}
+
+// writeToposort writes the modules one per line, dependencies before the
+// modules requiring them, each followed by its license with -licenses. Each cycle found is reported on stderr, and its
+// members are written next to each other.
+func writeToposort(w io.Writer, m *module) error {
+	for _, group := range m.topologicalOrder() {
+		if len(group) > 1 {
+			names := make([]string, 0, len(group))
+			for _, i := range group {
+				names = append(names, m.Indexes[i])
+			}
+			fmt.Fprintln(os.Stderr, "Cycle between "+strings.Join(names, ", ")+", listed together")
+		}
+		for _, i := range group {
+			if _, err := fmt.Fprintln(w, m.Indexes[i]+m.licenseSuffix(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestToposort(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-format", "toposort")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `example.com/c v1.0.0
+example.com/b v1.0.0
+example.com/a v1.0.0
+example.com/b v1.1.0
+example.com/missing v1.0.0
+example.com/app
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestTopologicalOrderCycle(t *testing.T) {
+	m := newModule(deptree.NewGraph())
+	for _, name := range []string{"root", "a v1.0.0", "b v1.0.0", "c v1.0.0"} {
+		m.Index(name)
+	}
+	// a and b require each other, so they have to be listed together.
+	m.Packages[0] = []int{1}
+	m.Packages[1] = []int{2}
+	m.Packages[2] = []int{1, 3}
+	m.Packages[3] = []int{}
+
+	want := [][]int{{3}, {1, 2}, {0}}
+	if got := m.topologicalOrder(); !reflect.DeepEqual(got, want) {
+		t.Errorf("topologicalOrder() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// printTree writes the module at index i and everything it requires as an
+// indented tree, stopping at the given depth. A negative depth means no limit.
+//
+// With -dedupeSubtrees the first time a module's requirements are printed they
+// are tagged with a subtree id, e.g. "example.com/x v1.0.0 [#1]:", and any
+// later occurrence of the module refers back to it as
+// "example.com/x v1.0.0 [see #1]" instead of repeating the whole subtree.
+func (m *module) printTree(w io.Writer, i int, indent string, depth int) {
+	children, resolved := m.Packages[i]
+	if depth == 0 || !resolved || m.onStack[i] {
+		fmt.Fprintln(w, indent+m.treeLabel(i))
+		return
+	}
+
+	if *dedupeSubtrees && len(children) > 0 {
+		if id, ok := m.subtrees[i]; ok && deeper(m.subtreeDepths[i], depth) {
+			fmt.Fprintf(w, "%s%s [see #%d]\n", indent, m.treeLabel(i), id)
+			return
+		}
+		id := len(m.subtrees) + 1
+		m.subtrees[i] = id
+		m.subtreeDepths[i] = depth
+		fmt.Fprintf(w, "%s%s [#%d]:\n", indent, m.treeLabel(i), id)
+	} else {
+		fmt.Fprintln(w, indent+m.treeLabel(i)+":")
+	}
+
+	m.onStack[i] = true
+	for _, child := range children {
+		m.printTree(w, child, indent+*indentText, depth-1)
+	}
+	delete(m.onStack, i)
+}
+
+// treeLabel returns the name of the module at index i as the tree prints it,
+// followed by the module replacing it or a note that it's excluded, and its
+// license if they were read.
+func (m *module) treeLabel(i int) string {
+	label := m.Indexes[i]
+	if r, ok := m.Replaced[i]; ok {
+		label += " => " + strings.TrimSpace(r.New+" "+r.NewVersion)
+	} else if _, ok := m.Excluded[i]; ok {
+		label += " (excluded)"
+	}
+	return label + m.licenseSuffix(i)
+}
+
+// licenseSuffix returns the license of the module at index i to append to
+// its label, empty if licenses weren't read.
+func (m *module) licenseSuffix(i int) string {
+	if license, ok := m.licenses[i]; ok {
+		return " [" + license + "]"
+	}
+	return ""
+}
+
+// deeper reports whether a walk limited to depth a goes at least as deep as
+// one limited to depth b, a negative depth meaning no limit.
+func deeper(a, b int) bool {
+	return a < 0 || (b >= 0 && a >= b)
+}
+
+// printDeprecated writes every deprecated module along with its deprecation
+// message.
+func printDeprecated(w io.Writer, m *module) {
+	if len(m.Deprecated) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "Deprecated:")
+	for i, name := range m.Indexes {
+		if message, ok := m.Deprecated[i]; ok {
+			fmt.Fprintln(w, "  "+name+": "+message)
+		}
+	}
+}
+
+// printDepths writes the fewest requires separating each module from the
+// root.
+func printDepths(w io.Writer, m *module) {
+	depths := m.Depths()
+	fmt.Fprintln(w, "Depths:")
+	for i, name := range m.Indexes[1:] {
+		if depth, ok := depths[i+1]; ok {
+			fmt.Fprintf(w, "  %s: %d\n", name, depth)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestDeprecated(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/legacy")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `example.com/legacy:
+  example.com/c v1.0.0:
+  example.com/old v1.0.0:
+Deprecated:
+  example.com/old v1.0.0: use example.com/c instead, which does everything old did.
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+
+	stdout, stderr, code = run(t, "example.com/legacy", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var graph jsonGraph
+	if err := json.Unmarshal([]byte(stdout), &graph); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, stdout)
+	}
+	wantDeprecated := map[string]string{
+		"example.com/old v1.0.0": "use example.com/c instead, which does everything old did.",
+	}
+	if !reflect.DeepEqual(graph.Deprecated, wantDeprecated) {
+		t.Errorf("deprecated = %q, want %q", graph.Deprecated, wantDeprecated)
+	}
+}
+
+func TestNotDeprecated(t *testing.T) {
+	stdout, _, _ := run(t, "example.com/clean")
+	if want := "example.com/clean:\n  example.com/c v1.0.0:\n"; stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+}
+
+func TestDepths(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-depths")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	// example.com/c is three requires down the first branch walked, but only
+	// two down the second.
+	want := `Depths:
+  example.com/a v1.0.0: 1
+  example.com/b v1.0.0: 2
+  example.com/c v1.0.0: 2
+  example.com/b v1.1.0: 1
+  example.com/missing v1.0.0: 1
+`
+	if !strings.HasSuffix(stdout, want) {
+		t.Errorf("output =\n%s\nwant it to end with\n%s", stdout, want)
+	}
+}
+
+func TestReplacedAndExcluded(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/replacing")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `example.com/replacing:
+  example.com/a v1.0.0:
+    example.com/b v1.0.0:
+      example.com/c v1.0.0 (excluded)
+    example.com/c v1.0.0 (excluded)
+  example.com/b v1.1.0 => ../clean:
+    example.com/c v1.0.0 (excluded)
+`
+	if stdout != want {
+		t.Errorf("output =\n%s\nwant\n%s", stdout, want)
+	}
+
+	stdout, stderr, code = run(t, "example.com/replacing", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var graph jsonGraph
+	if err := json.Unmarshal([]byte(stdout), &graph); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, stdout)
+	}
+	wantReplaced := map[string]deptree.ReplaceDirective{
+		"example.com/b v1.1.0": {Old: "example.com/b", OldVersion: "v1.1.0", New: "../clean"},
+	}
+	if !reflect.DeepEqual(graph.Replaced, wantReplaced) {
+		t.Errorf("replaced = %+v, want %+v", graph.Replaced, wantReplaced)
+	}
+	if want := []string{"example.com/c v1.0.0"}; !reflect.DeepEqual(graph.Excluded, want) {
+		t.Errorf("excluded = %q, want %q", graph.Excluded, want)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+// treeDiff is the difference between the versions two dependency trees
+// resolve every module path to.
+type treeDiff struct {
+	Added   []treeChange `json:"added"`
+	Removed []treeChange `json:"removed"`
+	Changed []treeChange `json:"changed"`
+}
+
+// treeChange is a module path added to, removed from or resolved to a
+// different version in the second tree, with the modules requiring the
+// version that made the difference.
+type treeChange struct {
+	Module     string   `json:"module"`
+	From       string   `json:"from,omitempty"`
+	To         string   `json:"to,omitempty"`
+	RequiredBy []string `json:"requiredBy"`
+}
+
+// buildTree builds the dependency tree of the module at dir, which may also
+// be its go.mod.
+func buildTree(dir string, opts ...deptree.Option) (*module, error) {
+	if info, err := os.Stat(dir); err == nil && !info.IsDir() && path.Base(dir) == "go.mod" {
+		dir = path.Dir(dir)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	g, err := deptree.Build(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return newModule(g), nil
+}
+
+// parentsOf returns the modules requiring the module named name, sorted.
+func (m *module) parentsOf(name string) []string {
+	parents := make([]string, 0)
+	child, ok := m.Lookup[name]
+	if !ok {
+		return parents
+	}
+	for parent, children := range m.Packages {
+		for _, c := range children {
+			if c == child {
+				parents = append(parents, m.Indexes[parent])
+				break
+			}
+		}
+	}
+	sort.Strings(parents)
+	return parents
+}
+
+// diffTrees compares the versions minimal version selection picks for every
+// module path in two trees. Comparing what the build would use, rather than
+// every version required anywhere, keeps the report to what really changed.
+func diffTrees(from, to *module) treeDiff {
+	before := make(map[string]string)
+	for _, r := range from.resolvedVersions() {
+		before[r.Module] = r.Selected
+	}
+	after := make(map[string]string)
+	for _, r := range to.resolvedVersions() {
+		after[r.Module] = r.Selected
+	}
+
+	diff := treeDiff{
+		Added:   make([]treeChange, 0),
+		Removed: make([]treeChange, 0),
+		Changed: make([]treeChange, 0),
+	}
+	for modPath, version := range after {
+		change := treeChange{Module: modPath, To: version, RequiredBy: to.parentsOf(modPath + " " + version)}
+		if old, ok := before[modPath]; !ok {
+			diff.Added = append(diff.Added, change)
+		} else if old != version {
+			change.From = old
+			diff.Changed = append(diff.Changed, change)
+		}
+	}
+	for modPath, version := range before {
+		if _, ok := after[modPath]; !ok {
+			diff.Removed = append(diff.Removed, treeChange{Module: modPath, From: version, RequiredBy: from.parentsOf(modPath + " " + version)})
+		}
+	}
+	for _, changes := range [][]treeChange{diff.Added, diff.Removed, diff.Changed} {
+		sort.Slice(changes, func(a, b int) bool {
+			return changes[a].Module < changes[b].Module
+		})
+	}
+	return diff
+}
+
+// writeTreeDiff writes the difference between two dependency trees.
+func writeTreeDiff(w io.Writer, format string, diff treeDiff) error {
+	if format == "json" {
+		return writeJSONValue(w, diff, &treeDiff{})
+	}
+	fmt.Fprintln(w, "Added:")
+	for _, change := range diff.Added {
+		fmt.Fprintln(w, "  "+change.Module+" "+change.To+" (required by "+strings.Join(change.RequiredBy, ", ")+")")
+	}
+	fmt.Fprintln(w, "Removed:")
+	for _, change := range diff.Removed {
+		fmt.Fprintln(w, "  "+change.Module+" "+change.From+" (was required by "+strings.Join(change.RequiredBy, ", ")+")")
+	}
+	fmt.Fprintln(w, "Changed:")
+	for _, change := range diff.Changed {
+		fmt.Fprintln(w, "  "+change.Module+": "+change.From+" -> "+change.To+" (required by "+strings.Join(change.RequiredBy, ", ")+")")
+	}
+	return nil
+}
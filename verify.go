@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+	gomodule "golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/mod/sumdb/dirhash"
+)
+
+// sumVerification is the result of checking the graph against the root
+// module's go.sum.
+type sumVerification struct {
+	// Missing holds the modules in the graph go.sum has no hash for.
+	Missing []string `json:"missing"`
+	// Pruned holds the modules go.sum has no hash for that a root module at
+	// go 1.17 or later doesn't need one for, as graph pruning stops the go
+	// command loading the go.mod of modules the root doesn't list. They're
+	// warned about rather than failing.
+	Pruned []string `json:"pruned"`
+	// Mismatched holds the modules whose source or go.mod in the module
+	// cache doesn't hash to what go.sum records, only checked with
+	// -verifyHashes.
+	Mismatched []hashMismatch `json:"mismatched"`
+	// Unreachable holds the modules go.sum has hashes for that aren't in the
+	// graph, which go mod tidy would remove.
+	Unreachable []string `json:"unreachable"`
+}
+
+// hashMismatch is a file of a module whose hash differs from go.sum. File
+// is either the module's source, as its zip is hashed, or its go.mod.
+type hashMismatch struct {
+	Module   string `json:"module"`
+	File     string `json:"file"`
+	GoSum    string `json:"goSum"`
+	Computed string `json:"computed"`
+}
+
+// failed reports whether the graph can't be trusted to match go.sum.
+// Unreachable hashes are harmless, so don't count.
+func (v *sumVerification) failed() bool {
+	return len(v.Missing) > 0 || len(v.Mismatched) > 0
+}
+
+// readGoSum reads every hash in the go.sum in dir, keyed by module name as
+// stored in Indexes, with /go.mod appended for the hash of the go.mod alone.
+// A missing go.sum has no hashes.
+func readGoSum(dir string) (map[string]string, error) {
+	sums := make(map[string]string)
+	fileBytes, err := ioutil.ReadFile(path.Join(dir, "go.sum"))
+	if os.IsNotExist(err) {
+		return sums, nil
+	} else if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(fileBytes), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		sums[fields[0]+" "+fields[1]] = fields[2]
+	}
+	return sums, nil
+}
+
+// sumName returns the name go.sum records the module at index i under,
+// which is its replacement's if the root module replaces it with another
+// module, and false if it isn't recorded at all, being the root or replaced
+// with a directory.
+func (m *module) sumName(i int) (string, bool) {
+	if i == 0 {
+		return "", false
+	}
+	name := m.Indexes[i]
+	if r, ok := m.Replacement(name); ok {
+		if r.IsLocal() {
+			return "", false
+		}
+		return r.New + " " + r.NewVersion, true
+	}
+	if _, version := deptree.SplitModuleName(name); version == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// verifySums checks every module in the graph against the root module's
+// go.sum, and every hash in go.sum against the graph. If hashes is set, the
+// source and go.mod of each module in the module cache are hashed too, at
+// most jobs at a time, as go mod verify does. Modules read from vendor are
+// never hashed, as vendoring leaves files out. If the root module is at go
+// 1.17 or later, only the modules it lists need a hash, as graph pruning
+// leaves the rest out of go.sum.
+func (m *module) verifySums(hashes bool, jobs int) error {
+	sums, err := readGoSum(m.Dir)
+	if err != nil {
+		return err
+	}
+	v := &sumVerification{
+		Missing:     make([]string, 0),
+		Pruned:      make([]string, 0),
+		Mismatched:  make([]hashMismatch, 0),
+		Unreachable: make([]string, 0),
+	}
+
+	// listed holds the names of the modules needing a hash, nil if they all
+	// do.
+	var listed map[string]bool
+	if goVersion := m.Metadata[0].Go; goVersion != "" && semver.Compare("v"+goVersion, "v1.17") >= 0 {
+		listed = make(map[string]bool)
+		for _, child := range m.Packages[0] {
+			if name, ok := m.sumName(child); ok {
+				listed[name] = true
+			}
+		}
+	}
+
+	inGraph := make(map[string]bool)
+	toHash := make([]int, 0)
+	for i := range m.Indexes {
+		if _, ok := m.Excluded[i]; ok {
+			continue
+		}
+		name, ok := m.sumName(i)
+		if !ok || inGraph[name] {
+			continue
+		}
+		inGraph[name] = true
+		if _, ok := sums[name+"/go.mod"]; !ok {
+			if _, ok := sums[name]; !ok {
+				if listed == nil || listed[name] {
+					v.Missing = append(v.Missing, name)
+				} else {
+					v.Pruned = append(v.Pruned, name)
+				}
+				continue
+			}
+		}
+		toHash = append(toHash, i)
+	}
+	unreachable := make(map[string]bool)
+	for entry := range sums {
+		name := strings.TrimSuffix(entry, "/go.mod")
+		if !inGraph[name] && !unreachable[name] {
+			unreachable[name] = true
+			v.Unreachable = append(v.Unreachable, name)
+		}
+	}
+
+	if hashes && !m.Options().Vendor {
+		mismatches := make([][]hashMismatch, len(toHash))
+		errs := make([]error, len(toHash))
+		limit := make(chan struct{}, jobs)
+		var wg sync.WaitGroup
+		for pos, i := range toHash {
+			wg.Add(1)
+			go func(pos, i int) {
+				defer wg.Done()
+				limit <- struct{}{}
+				defer func() { <-limit }()
+				mismatches[pos], errs[pos] = m.hashModule(i, sums)
+			}(pos, i)
+		}
+		wg.Wait()
+		for pos := range toHash {
+			if errs[pos] != nil {
+				return errs[pos]
+			}
+			v.Mismatched = append(v.Mismatched, mismatches[pos]...)
+		}
+	}
+
+	sort.Strings(v.Missing)
+	sort.Strings(v.Pruned)
+	sort.Strings(v.Unreachable)
+	sort.SliceStable(v.Mismatched, func(a, b int) bool {
+		return v.Mismatched[a].Module < v.Mismatched[b].Module
+	})
+	m.sums = v
+	return nil
+}
+
+// hashModule hashes the source and go.mod of the module at index i in the
+// module cache, returning those that differ from sums. Modules whose source
+// isn't in the module cache are skipped, even if they're checked out in
+// GOPATH, as a checkout isn't what go.sum hashes.
+func (m *module) hashModule(i int, sums map[string]string) ([]hashMismatch, error) {
+	name, _ := m.sumName(i)
+	modPath, version := deptree.SplitModuleName(name)
+	escapedPath, err := gomodule.EscapePath(modPath)
+	if err != nil {
+		return nil, nil
+	}
+	escapedVersion, err := gomodule.EscapeVersion(version)
+	if err != nil {
+		return nil, nil
+	}
+	modCache := m.Options().ModCache
+	dir := path.Join(modCache, escapedPath+"@"+escapedVersion)
+	if info, err := os.Stat(dir); modCache == "" || err != nil || !info.IsDir() {
+		return nil, nil
+	}
+	mismatches := make([]hashMismatch, 0)
+	if want, ok := sums[name]; ok {
+		got, err := dirhash.HashDir(dir, modPath+"@"+version, dirhash.Hash1)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %s: %v", name, err)
+		}
+		if got != want {
+			mismatches = append(mismatches, hashMismatch{Module: name, File: "source", GoSum: want, Computed: got})
+		}
+	}
+	// Modules without a go.mod of their own get one made up by the go
+	// command, which isn't in their source to hash.
+	modFile := path.Join(dir, "go.mod")
+	if want, ok := sums[name+"/go.mod"]; ok {
+		if _, err := os.Stat(modFile); err == nil {
+			got, err := dirhash.Hash1([]string{"go.mod"}, func(string) (io.ReadCloser, error) {
+				return os.Open(modFile)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("hashing the go.mod of %s: %v", name, err)
+			}
+			if got != want {
+				mismatches = append(mismatches, hashMismatch{Module: name, File: "go.mod", GoSum: want, Computed: got})
+			}
+		}
+	}
+	return mismatches, nil
+}
+
+// printSumVerification writes what checking the graph against go.sum found,
+// each heading prefixed by prefix.
+func printSumVerification(w io.Writer, prefix string, v *sumVerification) {
+	fmt.Fprintln(w, prefix+"Missing from go.sum:")
+	for _, name := range v.Missing {
+		fmt.Fprintln(w, "  "+name)
+	}
+	if len(v.Pruned) > 0 {
+		fmt.Fprintln(w, prefix+"Missing from go.sum, but left out by graph pruning (warning):")
+		for _, name := range v.Pruned {
+			fmt.Fprintln(w, "  "+name)
+		}
+	}
+	fmt.Fprintln(w, prefix+"Hashes not matching go.sum:")
+	for _, mismatch := range v.Mismatched {
+		fmt.Fprintf(w, "  %s %s: go.sum has %s, computed %s\n", mismatch.Module, mismatch.File, mismatch.GoSum, mismatch.Computed)
+	}
+	fmt.Fprintln(w, prefix+"Unreachable go.sum entries:")
+	for _, name := range v.Unreachable {
+		fmt.Fprintln(w, "  "+name)
+	}
+}
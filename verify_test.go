@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/clean", "-verify")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0 as go.sum has every module, stderr:\n%s", code, stderr)
+	}
+	if !strings.Contains(stdout, "Missing from go.sum:\nHashes not matching go.sum:\nUnreachable go.sum entries:\n") {
+		t.Errorf("output =\n%s\nwant empty reports", stdout)
+	}
+
+	// example.com/app has no go.sum at all.
+	_, stderr, code = run(t, "example.com/app", "-verify")
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1, stderr:\n%s", code, stderr)
+	}
+	want := `Missing from go.sum:
+  example.com/a v1.0.0
+  example.com/b v1.0.0
+  example.com/b v1.1.0
+  example.com/c v1.0.0
+  example.com/missing v1.0.0
+`
+	if !strings.HasPrefix(stderr, want) {
+		t.Errorf("stderr =\n%s\nwant it to start with\n%s", stderr, want)
+	}
+}
+
+func TestVerifyHashes(t *testing.T) {
+	// The fixture's go.sum doesn't hold the real hashes of example.com/c.
+	_, stderr, code := run(t, "example.com/clean", "-verify", "-verifyHashes")
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1, stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{
+		"  example.com/c v1.0.0 source: go.sum has h1:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU=, computed h1:",
+		"  example.com/c v1.0.0 go.mod: go.sum has h1:2PlPCf1GhvyT0XBAyvN+3vmQ9ldrF9N+SnzTY/nFSDU=, computed h1:QWUFs4x13a4cZSXx8RA7yKX4/9d+AnTE3Ra2t5V8gAQ=\n",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr =\n%s\nwant it to contain\n%s", stderr, want)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+	gomodule "golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// versionSpread is a module path that is required at more than one version
+// somewhere in the graph.
+type versionSpread struct {
+	Module   string   `json:"module"`
+	Lowest   string   `json:"lowest"`
+	Highest  string   `json:"highest"`
+	Versions []string `json:"versions"`
+}
+
+// moduleVersions groups the versions of every module in the graph by module
+// path, each list sorted from lowest to highest.
+func (m *module) moduleVersions() map[string][]string {
+	versions := make(map[string][]string)
+	for _, name := range m.Indexes[1:] {
+		modPath, version := deptree.SplitModuleName(name)
+		if version == "" {
+			continue
+		}
+		versions[modPath] = append(versions[modPath], version)
+	}
+	for _, list := range versions {
+		// Versions differing only in build metadata compare equal, so keep
+		// them in the order they were found.
+		sort.SliceStable(list, func(a, b int) bool {
+			return semver.Compare(list[a], list[b]) < 0
+		})
+	}
+	return versions
+}
+
+// downgradeRisks returns the module paths required at versions spanning more
+// than one major or minor release. Minimal version selection will pick the
+// highest of them for the real build, so the parts of the tree asking for the
+// lowest are the ones at risk when upgrading.
+func (m *module) downgradeRisks() []versionSpread {
+	risks := make([]versionSpread, 0)
+	for modPath, versions := range m.moduleVersions() {
+		lowest, highest := versions[0], versions[len(versions)-1]
+		if semver.MajorMinor(lowest) == semver.MajorMinor(highest) {
+			continue
+		}
+		risks = append(risks, versionSpread{
+			Module:   modPath,
+			Lowest:   lowest,
+			Highest:  highest,
+			Versions: versions,
+		})
+	}
+	sort.Slice(risks, func(a, b int) bool {
+		return risks[a].Module < risks[b].Module
+	})
+	return risks
+}
+
+// fragmentedModules returns the module paths required at more than max
+// distinct versions across the graph.
+func (m *module) fragmentedModules(max int) []versionSpread {
+	fragmented := make([]versionSpread, 0)
+	for modPath, versions := range m.moduleVersions() {
+		if len(versions) <= max {
+			continue
+		}
+		fragmented = append(fragmented, versionSpread{
+			Module:   modPath,
+			Lowest:   versions[0],
+			Highest:  versions[len(versions)-1],
+			Versions: versions,
+		})
+	}
+	sort.Slice(fragmented, func(a, b int) bool {
+		return fragmented[a].Module < fragmented[b].Module
+	})
+	return fragmented
+}
+
+// versionConflict is a module path required at more than one version, or
+// found alongside another major version of the same module, with the modules
+// requiring each version and the shortest path of requires pulling it in.
+type versionConflict struct {
+	Module string `json:"module"`
+	// Base is the module path without its major version suffix, which every
+	// major version of the module shares.
+	Base string `json:"base"`
+	// Majors holds the module paths of the other major versions of the
+	// module in the graph.
+	Majors   []string             `json:"majors,omitempty"`
+	Versions []conflictingVersion `json:"versions"`
+}
+
+// conflictingVersion is one of the versions of a versionConflict.
+type conflictingVersion struct {
+	Version    string   `json:"version"`
+	RequiredBy []string `json:"requiredBy"`
+	Path       []string `json:"path"`
+}
+
+// majorBase returns the module path without its major version suffix, such
+// as example.com/x for example.com/x/v2.
+func majorBase(modPath string) string {
+	if prefix, _, ok := gomodule.SplitPathVersion(modPath); ok {
+		return prefix
+	}
+	return modPath
+}
+
+// duplicateMajors groups the module paths in the graph by majorBase, and
+// returns the groups with more than one major version, each sorted. Both
+// -failOnDuplicateMajor and the noDuplicateMajors policy rule fail on them.
+func (m *module) duplicateMajors() map[string][]string {
+	byBase := make(map[string][]string)
+	for modPath := range m.moduleVersions() {
+		base := majorBase(modPath)
+		byBase[base] = append(byBase[base], modPath)
+	}
+	for base, paths := range byBase {
+		if len(paths) < 2 {
+			delete(byBase, base)
+			continue
+		}
+		sort.Strings(paths)
+	}
+	return byBase
+}
+
+// conflicts returns every module path required at more than one version
+// across the graph, or found at more than one major version, each version
+// listed from lowest to highest along with the modules requiring it and a
+// path pulling it in. Seeing who asks for what is the quickest way to
+// understand which version minimal version selection will pick and why.
+// Major versions of the same module are listed next to each other.
+func (m *module) conflicts() []versionConflict {
+	requiredBy := make(map[string][]string)
+	for parent, children := range m.Packages {
+		for _, child := range children {
+			requiredBy[m.Indexes[child]] = append(requiredBy[m.Indexes[child]], m.Indexes[parent])
+		}
+	}
+	majors := m.duplicateMajors()
+
+	conflicts := make([]versionConflict, 0)
+	for modPath, versions := range m.moduleVersions() {
+		base := majorBase(modPath)
+		if len(versions) < 2 && len(majors[base]) == 0 {
+			continue
+		}
+		conflict := versionConflict{Module: modPath, Base: base}
+		if paths, ok := majors[base]; ok {
+			conflict.Majors = others(paths, modPath)
+		}
+		for _, version := range versions {
+			name := modPath + " " + version
+			parents := requiredBy[name]
+			sort.Strings(parents)
+			path := make([]string, 0)
+			if paths := m.shortestPaths(name); len(paths) > 0 {
+				for _, i := range paths[0] {
+					path = append(path, m.Indexes[i])
+				}
+			}
+			conflict.Versions = append(conflict.Versions, conflictingVersion{
+				Version:    version,
+				RequiredBy: parents,
+				Path:       path,
+			})
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	sort.Slice(conflicts, func(a, b int) bool {
+		if conflicts[a].Base != conflicts[b].Base {
+			return conflicts[a].Base < conflicts[b].Base
+		}
+		return conflicts[a].Module < conflicts[b].Module
+	})
+	return conflicts
+}
+
+// printConflicts writes each of conflicts under heading, grouped by the
+// module path they share without their major version suffix. Each major
+// version comes with its spread of versions and a path pulling in each.
+func printConflicts(w io.Writer, heading string, conflicts []versionConflict) {
+	fmt.Fprintln(w, heading)
+	for pos, conflict := range conflicts {
+		if pos == 0 || conflicts[pos-1].Base != conflict.Base {
+			if len(conflict.Majors) > 0 {
+				fmt.Fprintf(w, "  %s (%d major versions):\n", conflict.Base, len(conflict.Majors)+1)
+			} else {
+				fmt.Fprintf(w, "  %s:\n", conflict.Base)
+			}
+		}
+		lowest, highest := conflict.Versions[0].Version, conflict.Versions[len(conflict.Versions)-1].Version
+		if lowest == highest {
+			fmt.Fprintf(w, "    %s %s\n", conflict.Module, lowest)
+		} else {
+			fmt.Fprintf(w, "    %s %s .. %s\n", conflict.Module, lowest, highest)
+		}
+		for _, version := range conflict.Versions {
+			fmt.Fprintln(w, "      "+version.Version+": "+strings.Join(version.Path, " -> "))
+		}
+	}
+}
+
+// invalidVersions returns the modules whose version isn't valid semver,
+// which points to a corrupt go.mod or a bug parsing one.
+func (m *module) invalidVersions() []string {
+	invalid := make([]string, 0)
+	for _, name := range m.Indexes[1:] {
+		if _, version := deptree.SplitModuleName(name); !semver.IsValid(version) {
+			invalid = append(invalid, name)
+		}
+	}
+	return invalid
+}
+
+// printInvalidVersions writes every module whose version isn't valid semver.
+func printInvalidVersions(w io.Writer, m *module) {
+	fmt.Fprintln(w, "Invalid versions:")
+	for _, name := range m.invalidVersions() {
+		fmt.Fprintln(w, "  "+name)
+	}
+}
+
+// printDowngradeRisks writes every module path at risk of a version bump.
+func printDowngradeRisks(w io.Writer, m *module) {
+	fmt.Fprintln(w, "Downgrade risks:")
+	for _, risk := range m.downgradeRisks() {
+		fmt.Fprintf(w, "  %s: %s .. %s (%s)\n", risk.Module, risk.Lowest, risk.Highest, strings.Join(risk.Versions, ", "))
+	}
+}
+
+// resolvedVersion is the version of a module path minimal version selection
+// picks for the build, along with every version required across the graph.
+type resolvedVersion struct {
+	Module   string   `json:"module"`
+	Selected string   `json:"selected"`
+	Versions []string `json:"versions"`
+}
+
+// resolvedVersions returns the version minimal version selection picks for
+// every module path in the graph: the highest version the graph reaches. If
+// the root module excludes that version, the go command moves on to the next
+// higher version that isn't excluded, which is looked up on the module proxy,
+// and whose own requirements the graph hasn't walked. Paths without such a
+// version are left out. The graph only reaches every version the build
+// considers if it was walked whole, which main makes sure of. The result is
+// worked out once and kept.
+func (m *module) resolvedVersions() []resolvedVersion {
+	if m.resolved != nil {
+		return m.resolved
+	}
+	resolved := make([]resolvedVersion, 0)
+	for modPath, versions := range m.moduleVersions() {
+		selected := versions[len(versions)-1]
+		if _, ok := m.Excludes[modPath+" "+selected]; ok {
+			selected = m.nextVersion(modPath, selected)
+		}
+		if selected == "" {
+			continue
+		}
+		resolved = append(resolved, resolvedVersion{
+			Module:   modPath,
+			Selected: selected,
+			Versions: versions,
+		})
+	}
+	sort.Slice(resolved, func(a, b int) bool {
+		return resolved[a].Module < resolved[b].Module
+	})
+	m.resolved = resolved
+	return resolved
+}
+
+// nextVersion returns the lowest version of modPath on the module proxy
+// higher than version that the root module doesn't exclude, preferring
+// releases to pre-releases as the go command does. It returns an empty string
+// if there's none, or the proxy can't be asked.
+func (m *module) nextVersion(modPath, version string) string {
+	versions, err := m.Options().ProxyVersions(modPath)
+	if err != nil {
+		return ""
+	}
+	next := ""
+	for _, v := range versions {
+		if semver.Compare(v, version) <= 0 {
+			continue
+		}
+		if _, ok := m.Excludes[modPath+" "+v]; ok {
+			continue
+		}
+		if semver.Prerelease(v) == "" {
+			return v
+		}
+		if next == "" {
+			next = v
+		}
+	}
+	return next
+}
+
+// printResolvedVersions writes the version selected for every module path,
+// followed by the versions required across the graph where there's more than
+// one.
+func printResolvedVersions(w io.Writer, m *module) {
+	fmt.Fprintln(w, "Resolved versions:")
+	for _, r := range m.resolvedVersions() {
+		if len(r.Versions) == 1 {
+			fmt.Fprintf(w, "  %s %s\n", r.Module, r.Selected)
+			continue
+		}
+		fmt.Fprintf(w, "  %s %s (required at %s)\n", r.Module, r.Selected, strings.Join(r.Versions, ", "))
+	}
+}
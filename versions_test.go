@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConflicts(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/majors", "-conflicts")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	want := `Module conflicts:
+  example.com/c (2 major versions):
+    example.com/c v1.0.0
+      v1.0.0: example.com/majors -> example.com/b v1.1.0 -> example.com/c v1.0.0
+    example.com/c/v2 v2.0.0
+      v2.0.0: example.com/majors -> example.com/c/v2 v2.0.0
+`
+	if !strings.HasSuffix(stdout, want) {
+		t.Errorf("output =\n%s\nwant it to end with\n%s", stdout, want)
+	}
+
+	stdout, stderr, code = run(t, "example.com/app", "-conflicts", "-format", "json")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	var graph jsonGraph
+	if err := json.Unmarshal([]byte(stdout), &graph); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, stdout)
+	}
+	wantConflicts := []versionConflict{{
+		Module: "example.com/b",
+		Base:   "example.com/b",
+		Versions: []conflictingVersion{
+			{
+				Version:    "v1.0.0",
+				RequiredBy: []string{"example.com/a v1.0.0"},
+				Path:       []string{"example.com/app", "example.com/a v1.0.0", "example.com/b v1.0.0"},
+			},
+			{
+				Version:    "v1.1.0",
+				RequiredBy: []string{"example.com/app"},
+				Path:       []string{"example.com/app", "example.com/b v1.1.0"},
+			},
+		},
+	}}
+	if !reflect.DeepEqual(graph.Conflicts, wantConflicts) {
+		t.Errorf("conflicts = %+v, want %+v", graph.Conflicts, wantConflicts)
+	}
+}
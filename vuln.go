@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	gomodule "golang.org/x/mod/module"
+)
+
+// osvBatchSize is the most queries the OSV batch API takes in one request.
+const osvBatchSize = 1000
+
+// severities orders the severities advisories are rated with, lowest first.
+// medium is the name some databases use for moderate.
+var severities = map[string]int{
+	"low":      1,
+	"moderate": 2,
+	"medium":   2,
+	"high":     3,
+	"critical": 4,
+}
+
+// vulnerability is a known advisory against a module in the graph, along
+// with the shortest path of requires pulling the module in.
+type vulnerability struct {
+	ID string `json:"id"`
+	// Aliases holds the ids of the same advisory in other databases, which
+	// are only reported once, under ID.
+	Aliases  []string `json:"aliases,omitempty"`
+	Module   string   `json:"module"`
+	Summary  string   `json:"summary,omitempty"`
+	Severity string   `json:"severity,omitempty"`
+	Path     []string `json:"path"`
+}
+
+// atLeast reports whether the vulnerability is rated at or above the given
+// severity. Advisories without a rating, from their own database or any
+// alias, always count, as there's no telling they don't matter.
+func (v vulnerability) atLeast(severity string) bool {
+	rating, ok := severities[strings.ToLower(v.Severity)]
+	return !ok || rating >= severities[severity]
+}
+
+type osvQuery struct {
+	Package osvPackage `json:"package"`
+	Version string     `json:"version"`
+}
+
+type osvPackage struct {
+	Name      string `json:"name"`
+	Ecosystem string `json:"ecosystem"`
+}
+
+type osvBatchResponse struct {
+	Results []struct {
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	} `json:"results"`
+}
+
+type osvVulnerability struct {
+	Summary  string   `json:"summary"`
+	Aliases  []string `json:"aliases"`
+	Severity []struct {
+		Type  string `json:"type"`
+		Score string `json:"score"`
+	} `json:"severity"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity"`
+	} `json:"database_specific"`
+}
+
+// rating returns the severity the advisory is rated at, either by its
+// database or from its CVSS score, or an empty string if it isn't rated.
+func (v osvVulnerability) rating() string {
+	if v.DatabaseSpecific.Severity != "" {
+		return strings.ToLower(v.DatabaseSpecific.Severity)
+	}
+	for _, severity := range v.Severity {
+		if rating := severityRating(severity.Score); rating != "" {
+			return rating
+		}
+	}
+	return ""
+}
+
+// privateModules returns the module path patterns, from GOPRIVATE and
+// GONOSUMDB, of modules that mustn't be disclosed to public services.
+func privateModules() string {
+	patterns := make([]string, 0, 2)
+	for _, env := range []string{"GOPRIVATE", "GONOSUMDB"} {
+		if value := os.Getenv(env); value != "" {
+			patterns = append(patterns, value)
+		}
+	}
+	return strings.Join(patterns, ",")
+}
+
+var osvClient = &http.Client{Timeout: time.Minute}
+
+// readVulnerabilities queries the OSV API at baseURL for advisories against
+// the version of every module path minimal version selection picks, as those
+// are the versions the build really uses. Modules matching GOPRIVATE or
+// GONOSUMDB are never asked about, so their paths aren't sent to OSV. An
+// advisory found in several databases is reported once, under the first id
+// returned, along with the rating of whichever alias has one.
+func (m *module) readVulnerabilities(baseURL string) error {
+	baseURL = strings.TrimRight(baseURL, "/")
+	private := privateModules()
+	resolved := make([]resolvedVersion, 0)
+	for _, r := range m.resolvedVersions() {
+		if !gomodule.MatchPrefixPatterns(private, r.Module) {
+			resolved = append(resolved, r)
+		}
+	}
+	m.vulns = make([]vulnerability, 0)
+	details := make(map[string]*osvVulnerability)
+	// detail fetches the rest of an advisory once however many modules it
+	// affects, as the batch API only returns ids.
+	detail := func(id string) (*osvVulnerability, error) {
+		if d, ok := details[id]; ok {
+			return d, nil
+		}
+		d := &osvVulnerability{}
+		if err := osvRequest(http.MethodGet, baseURL+"/v1/vulns/"+url.PathEscape(id), nil, d); err != nil {
+			return nil, err
+		}
+		details[id] = d
+		return d, nil
+	}
+	for start := 0; start < len(resolved); start += osvBatchSize {
+		batch := resolved[start:]
+		if len(batch) > osvBatchSize {
+			batch = batch[:osvBatchSize]
+		}
+		queries := make([]osvQuery, 0, len(batch))
+		for _, r := range batch {
+			queries = append(queries, osvQuery{
+				Package: osvPackage{Name: r.Module, Ecosystem: "Go"},
+				// OSV records Go versions without the v prefix.
+				Version: strings.TrimPrefix(r.Selected, "v"),
+			})
+		}
+		var response osvBatchResponse
+		if err := osvRequest(http.MethodPost, baseURL+"/v1/querybatch", map[string][]osvQuery{"queries": queries}, &response); err != nil {
+			return err
+		}
+		if len(response.Results) != len(batch) {
+			return fmt.Errorf("querying OSV: asked about %d modules but got %d results", len(batch), len(response.Results))
+		}
+
+		for pos, result := range response.Results {
+			name := batch[pos].Module + " " + batch[pos].Selected
+			path := make([]string, 0)
+			if paths := m.shortestPaths(name); len(paths) > 0 {
+				for _, i := range paths[0] {
+					path = append(path, m.Indexes[i])
+				}
+			}
+			// found maps every id and alias of the advisories found for
+			// the module to the position of the one reported.
+			found := make(map[string]int)
+			for _, v := range result.Vulns {
+				d, err := detail(v.ID)
+				if err != nil {
+					return err
+				}
+				ids := append([]string{v.ID}, d.Aliases...)
+				at, seen := -1, false
+				for _, id := range ids {
+					if at, seen = found[id]; seen {
+						break
+					}
+				}
+				if !seen {
+					at = len(m.vulns)
+					m.vulns = append(m.vulns, vulnerability{
+						ID:      v.ID,
+						Module:  name,
+						Summary: d.Summary,
+						Path:    path,
+					})
+				} else if v.ID != m.vulns[at].ID {
+					m.vulns[at].Aliases = append(m.vulns[at].Aliases, v.ID)
+				}
+				for _, id := range ids {
+					found[id] = at
+				}
+				if m.vulns[at].Severity == "" {
+					m.vulns[at].Severity = d.rating()
+				}
+			}
+		}
+	}
+
+	// Advisories of the Go vulnerability database aren't rated, but their
+	// aliases in other databases usually are.
+	for pos := range m.vulns {
+		v := &m.vulns[pos]
+		if v.Severity != "" {
+			continue
+		}
+		for _, alias := range details[v.ID].Aliases {
+			// An alias OSV doesn't know, such as most CVE ids, just can't
+			// rate the advisory.
+			if d, err := detail(alias); err == nil && d.rating() != "" {
+				v.Severity = d.rating()
+				break
+			}
+		}
+	}
+	sort.SliceStable(m.vulns, func(a, b int) bool {
+		if m.vulns[a].Module != m.vulns[b].Module {
+			return m.vulns[a].Module < m.vulns[b].Module
+		}
+		return m.vulns[a].ID < m.vulns[b].ID
+	})
+	return nil
+}
+
+// osvRequest sends body, if it isn't nil, as JSON to the OSV API and decodes
+// the response into result.
+func osvRequest(method, endpoint string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := osvClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("querying OSV: %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// severeVulnerabilities returns the vulnerabilities rated at or above the
+// given severity.
+func (m *module) severeVulnerabilities(severity string) []vulnerability {
+	severe := make([]vulnerability, 0)
+	for _, v := range m.vulns {
+		if v.atLeast(severity) {
+			severe = append(severe, v)
+		}
+	}
+	return severe
+}
+
+// printVulnerabilities writes each of vulns under heading, with the path of
+// requires pulling in the vulnerable module.
+func printVulnerabilities(w io.Writer, heading string, vulns []vulnerability) {
+	fmt.Fprintln(w, heading)
+	for _, v := range vulns {
+		severity := v.Severity
+		if severity == "" {
+			severity = "unrated"
+		}
+		id := v.ID
+		if len(v.Aliases) > 0 {
+			id += " (also " + strings.Join(v.Aliases, ", ") + ")"
+		}
+		fmt.Fprintf(w, "  %s: %s (%s) %s\n", v.Module, id, severity, v.Summary)
+		fmt.Fprintln(w, "    "+strings.Join(v.Path, " -> "))
+	}
+}
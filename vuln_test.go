@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newOSVServer returns a stub of the OSV API reporting one advisory against
+// example.com/c v1.0.0, rated high through its CVE alias, and recording the
+// module paths it's asked about.
+func newOSVServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	asked := make([]string, 0)
+	advisories := map[string]string{
+		"GO-2024-0001":  `{"summary": "Crash on bad input", "aliases": ["CVE-2024-0001"]}`,
+		"CVE-2024-0001": `{"severity": [{"type": "CVSS_V3", "score": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H"}]}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/querybatch" {
+			var body struct {
+				Queries []osvQuery `json:"queries"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			results := make([]string, 0, len(body.Queries))
+			mu.Lock()
+			for _, q := range body.Queries {
+				asked = append(asked, q.Package.Name)
+			}
+			mu.Unlock()
+			for _, q := range body.Queries {
+				if q.Package.Name == "example.com/c" && q.Version == "1.0.0" {
+					results = append(results, `{"vulns": [{"id": "GO-2024-0001"}]}`)
+				} else {
+					results = append(results, `{}`)
+				}
+			}
+			w.Write([]byte(`{"results": [` + strings.Join(results, ",") + `]}`))
+			return
+		}
+		advisory, ok := advisories[strings.TrimPrefix(r.URL.Path, "/v1/vulns/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(advisory))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return asked
+	}
+}
+
+func TestVuln(t *testing.T) {
+	server, _ := newOSVServer(t)
+	stdout, stderr, code := run(t, "example.com/app", "-vuln", "-vulnUrl", server.URL)
+	if code != 1 {
+		t.Fatalf("exit code = %d, want 1, stderr:\n%s", code, stderr)
+	}
+	want := `Vulnerabilities:
+  example.com/c v1.0.0: GO-2024-0001 (high) Crash on bad input
+    example.com/app -> example.com/a v1.0.0 -> example.com/c v1.0.0
+`
+	if !strings.HasSuffix(stdout, want) {
+		t.Errorf("output =\n%s\nwant it to end with\n%s", stdout, want)
+	}
+	if !strings.Contains(stderr, "Vulnerabilities rated low or above:\n  example.com/c v1.0.0: GO-2024-0001") {
+		t.Errorf("stderr = %q, want the advisory reported", stderr)
+	}
+
+	_, stderr, code = run(t, "example.com/app", "-vuln", "-vulnUrl", server.URL, "-vulnSeverity", "critical")
+	if code != 0 {
+		t.Errorf("exit code = %d, want 0 as nothing is rated critical, stderr:\n%s", code, stderr)
+	}
+}
+
+func TestVulnSkipsPrivateModules(t *testing.T) {
+	server, asked := newOSVServer(t)
+	_, stderr, code := runEnv(t, []string{"GOPRIVATE=example.com/c"}, "example.com/app", "-vuln", "-vulnUrl", server.URL)
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	for _, modPath := range asked() {
+		if modPath == "example.com/c" {
+			t.Errorf("OSV was asked about %s, which GOPRIVATE matches", modPath)
+		}
+	}
+	if len(asked()) == 0 {
+		t.Errorf("OSV wasn't asked about any module")
+	}
+}
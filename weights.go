@@ -0,0 +1,148 @@
+package main
+
+import (
+	"math"
+	"math/bits"
+)
+
+// bitset is a fixed size set of module indexes.
+type bitset []uint64
+
+func newBitset(size int) bitset {
+	return make(bitset, (size+63)/64)
+}
+
+func (b bitset) add(i int) {
+	b[i/64] |= 1 << uint(i%64)
+}
+
+func (b bitset) union(other bitset) {
+	for i := range b {
+		b[i] |= other[i]
+	}
+}
+
+// countMissing returns how many members of b aren't in other.
+func (b bitset) countMissing(other bitset) int {
+	total := 0
+	for i, word := range b {
+		total += bits.OnesCount64(word &^ other[i])
+	}
+	return total
+}
+
+func (b bitset) count() int {
+	total := 0
+	for _, word := range b {
+		total += bits.OnesCount64(word)
+	}
+	return total
+}
+
+// subtreeSizes returns the number of distinct modules reachable from each
+// module, including the module itself.
+func (m *module) subtreeSizes() []int {
+	reachable := m.reachableSets()
+	sizes := make([]int, len(m.Indexes))
+	for i := range m.Indexes {
+		sizes[i] = reachable[i].count()
+	}
+	return sizes
+}
+
+// reachableSets returns the set of modules reachable from each module,
+// including the module itself. Modules in a cycle reach each other, so the
+// cycles are first collapsed into strongly connected components and the
+// reachable sets are then built up once per component, with every member of a
+// component sharing the same set.
+func (m *module) reachableSets() []bitset {
+	components, componentOf := m.stronglyConnectedComponents()
+
+	// Components are found in reverse topological order, so every component a
+	// component requires has already been built by the time we reach it.
+	reachable := make([]bitset, len(components))
+	for c, members := range components {
+		reachable[c] = newBitset(len(m.Indexes))
+		for _, i := range members {
+			reachable[c].add(i)
+			for _, child := range m.Packages[i] {
+				if componentOf[child] != c {
+					reachable[c].union(reachable[componentOf[child]])
+				}
+			}
+		}
+	}
+
+	sets := make([]bitset, len(m.Indexes))
+	for c, members := range components {
+		for _, i := range members {
+			sets[i] = reachable[c]
+		}
+	}
+	return sets
+}
+
+// stronglyConnectedComponents returns the strongly connected components of the
+// graph using Tarjan's algorithm, along with the component each module
+// belongs to.
+func (m *module) stronglyConnectedComponents() ([][]int, []int) {
+	var (
+		components  = make([][]int, 0)
+		componentOf = make([]int, len(m.Indexes))
+		order       = make([]int, len(m.Indexes))
+		lowLink     = make([]int, len(m.Indexes))
+		visited     = make([]bool, len(m.Indexes))
+		onStack     = make([]bool, len(m.Indexes))
+		stack       = make([]int, 0)
+		counter     = 0
+	)
+
+	var connect func(i int)
+	connect = func(i int) {
+		order[i] = counter
+		lowLink[i] = counter
+		counter++
+		visited[i] = true
+		stack = append(stack, i)
+		onStack[i] = true
+
+		for _, child := range m.Packages[i] {
+			if !visited[child] {
+				connect(child)
+				if lowLink[child] < lowLink[i] {
+					lowLink[i] = lowLink[child]
+				}
+			} else if onStack[child] && order[child] < lowLink[i] {
+				lowLink[i] = order[child]
+			}
+		}
+
+		if lowLink[i] == order[i] {
+			component := make([]int, 0)
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				componentOf[top] = len(components)
+				component = append(component, top)
+				if top == i {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+
+	for i := range m.Indexes {
+		if !visited[i] {
+			connect(i)
+		}
+	}
+	return components, componentOf
+}
+
+// edgeWidth scales an edge weight to a Graphviz pen width, growing slowly so
+// the heaviest edges don't swamp the rest of the graph.
+func edgeWidth(weight int) float64 {
+	return 1 + math.Log2(float64(weight))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kapilpau/go-mod-dependency-tree/pkg/deptree"
+)
+
+func TestSubtreeSizes(t *testing.T) {
+	m := newModule(deptree.NewGraph())
+	root := m.Index("example.com/app")
+	a := m.Index("example.com/a v1.0.0")
+	b := m.Index("example.com/b v1.0.0")
+	c := m.Index("example.com/c v1.0.0")
+	d := m.Index("example.com/d v1.0.0")
+	// b and c require each other, so each reaches the other along with d.
+	m.Packages[root] = []int{a, b}
+	m.Packages[a] = []int{d}
+	m.Packages[b] = []int{c}
+	m.Packages[c] = []int{b, d}
+
+	want := []int{5, 2, 3, 3, 1}
+	if got := m.subtreeSizes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("subtreeSizes() = %v, want %v", got, want)
+	}
+}
+
+func TestWeightsDOT(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-weights", "-format", "dot")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{
+		`  0 -> 1 [label="3", penwidth=2.58];`,
+		`  0 -> 4 [label="2", penwidth=2.00];`,
+		`  0 -> 5 [label="1", penwidth=1.00];`,
+		`  1 -> 2 [label="2", penwidth=2.00];`,
+	} {
+		if !strings.Contains(stdout, want+"\n") {
+			t.Errorf("output doesn't contain %s:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestWeightsMermaid(t *testing.T) {
+	stdout, stderr, code := run(t, "example.com/app", "-weights", "-format", "mermaid")
+	if code != 0 {
+		t.Fatalf("exit code = %d, want 0, stderr:\n%s", code, stderr)
+	}
+	for _, want := range []string{
+		"  m0 -->|3| m1",
+		"  m0 -->|2| m4",
+		"  m0 -->|1| m5",
+		"  m1 -->|2| m2",
+		"  m4 -->|1| m3",
+	} {
+		if !strings.Contains(stdout, want+"\n") {
+			t.Errorf("output doesn't contain %s:\n%s", want, stdout)
+		}
+	}
+}